@@ -2,6 +2,7 @@ package substrate
 
 import (
 	"bytes"
+	"context"
 	"crypto/ed25519"
 	"encoding/json"
 	"fmt"
@@ -99,7 +100,7 @@ curl --header "Content-Type: application/json" \
   https://api.substrate01.threefold.io/activate
 */
 
-func (s *Substrate) activateAccount(identity Identity, activationURL string) error {
+func (s *Substrate) activateAccount(ctx context.Context, identity Identity, activationURL string) error {
 	var buf bytes.Buffer
 	if err := json.NewEncoder(&buf).Encode(map[string]string{
 		"substrateAccountID": identity.Address(),
@@ -107,7 +108,13 @@ func (s *Substrate) activateAccount(identity Identity, activationURL string) err
 		return errors.Wrap(err, "failed to build required body")
 	}
 
-	response, err := http.Post(activationURL, "application/json", &buf)
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, activationURL, &buf)
+	if err != nil {
+		return errors.Wrap(err, "failed to build activation request")
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := http.DefaultClient.Do(request)
 	if err != nil {
 		return errors.Wrap(err, "failed to call activation service")
 	}
@@ -133,7 +140,7 @@ func (s *Substrate) EnsureAccount(identity Identity, activationURL, termsAndCond
 	if errors.Is(err, ErrAccountNotFound) {
 		// account activation
 		log.Debug().Msg("account not found ... activating")
-		if err = s.activateAccount(identity, activationURL); err != nil {
+		if err = s.activateAccount(s.Context(), identity, activationURL); err != nil {
 			return
 		}
 
@@ -147,7 +154,11 @@ func (s *Substrate) EnsureAccount(identity Identity, activationURL, termsAndCond
 		err = backoff.Retry(func() error {
 			info, err = s.getAccount(cl, meta, identity)
 			return err
-		}, exp)
+		}, backoff.WithContext(exp, s.Context()))
+	}
+
+	if err != nil {
+		return info, errors.Wrap(err, "failed to get account")
 	}
 
 	log.Info().Str("address", identity.Address()).Msg("account")
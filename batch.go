@@ -0,0 +1,148 @@
+package substrate
+
+import (
+	"fmt"
+
+	"github.com/centrifuge/go-substrate-rpc-client/v4/types"
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrNotExecuted is set for calls in a batch that were not executed
+	// because an earlier call in the same batch failed
+	ErrNotExecuted = fmt.Errorf("call not executed")
+)
+
+// CallResult is the outcome of a single call of a batch
+type CallResult struct {
+	// Events emitted by the call, the events of a failed call are reverted
+	Events EventRecords
+	// Error is nil if the call succeeded, it's ErrNotExecuted if the call was
+	// not executed because an earlier call in the same batch failed
+	Error error
+}
+
+// BatchResult is the outcome of a batch of calls
+type BatchResult struct {
+	Receipt
+	// Calls has the result of each call in the batch in the same order
+	Calls []CallResult
+}
+
+// Batch submits all calls in a single Utility.batch extrinsic. Calls are executed
+// in order until one of them fails, calls before the failed one are not reverted.
+// The error is only set if the batch extrinsic itself could not be submitted, check
+// the result Calls for the outcome and events of each call.
+func (s *Substrate) Batch(identity Identity, calls []types.Call, opts ...CallOption) (result BatchResult, err error) {
+	cl, meta, err := s.getClient()
+	if err != nil {
+		return result, err
+	}
+
+	return s.batch(cl, meta, identity, "Utility.batch", calls, opts...)
+}
+
+// BatchAll submits all calls in a single Utility.batch_all extrinsic. If any call
+// fails the whole batch is reverted and the error is returned for all calls.
+func (s *Substrate) BatchAll(identity Identity, calls []types.Call, opts ...CallOption) (result BatchResult, err error) {
+	cl, meta, err := s.getClient()
+	if err != nil {
+		return result, err
+	}
+
+	result, err = s.batch(cl, meta, identity, "Utility.batch_all", calls, opts...)
+	if err != nil {
+		return result, err
+	}
+
+	if result.Error != nil {
+		return result, errors.Wrap(result.Error, "batch reverted")
+	}
+
+	return result, nil
+}
+
+func (s *Substrate) batch(cl Conn, meta Meta, identity Identity, method string, calls []types.Call, opts ...CallOption) (result BatchResult, err error) {
+	if len(calls) == 0 {
+		return result, fmt.Errorf("no calls to batch")
+	}
+
+	c, err := types.NewCall(meta, method, calls)
+	if err != nil {
+		return result, errors.Wrap(err, "failed to create call")
+	}
+
+	receipt, err := s.Submit(cl, meta, identity, c, opts...)
+	result.Receipt = receipt
+
+	var dispatchErr *DispatchError
+	if err != nil && !errors.As(err, &dispatchErr) {
+		return result, errors.Wrap(err, "failed to submit batch")
+	}
+
+	// errors are resolved with the runtime the batch was executed by
+	if blockMeta, err := s.metas.at(cl, receipt.BlockHash); err == nil {
+		meta = blockMeta
+	}
+
+	result.Calls = batchResults(meta, &receipt, len(calls))
+	return result, nil
+}
+
+// batchResults splits the events of the batch at the ItemCompleted or ItemFailed
+// event emitted after each call. The fee withdrawal that precedes the calls is
+// not part of the first call.
+func batchResults(meta Meta, receipt *Receipt, count int) []CallResult {
+	results := make([]CallResult, count)
+
+	if receipt.Error != nil {
+		// batch_all failed, so all calls are reverted
+		for i := range results {
+			results[i].Error = receipt.Error
+		}
+
+		return results
+	}
+
+	call := 0
+	fee := true
+	var events EventRecords
+	for i := range receipt.ordered {
+		if call >= count {
+			break
+		}
+
+		event := &receipt.ordered[i].Event
+		switch {
+		case fee && len(event.Balances_Withdraw) > 0:
+			continue
+		case len(event.Utility_ItemCompleted) > 0:
+			results[call].Events = events
+			events = EventRecords{}
+			call++
+		case len(event.Utility_ItemFailed) > 0:
+			results[call].Events = events
+			results[call].Error = newDispatchError(meta, event.Utility_ItemFailed[0].DispatchError)
+			events = EventRecords{}
+			call++
+		case len(event.Utility_BatchInterrupted) > 0:
+			index := int(event.Utility_BatchInterrupted[0].Index)
+			if index >= count {
+				return results
+			}
+
+			results[index].Error = newDispatchError(meta, event.Utility_BatchInterrupted[0].DispatchError)
+			for j := index + 1; j < count; j++ {
+				results[j].Error = ErrNotExecuted
+			}
+
+			return results
+		default:
+			appendEvents(&events, event)
+		}
+
+		fee = false
+	}
+
+	return results
+}
@@ -0,0 +1,45 @@
+package substrate
+
+import (
+	"testing"
+
+	"github.com/centrifuge/go-substrate-rpc-client/v4/types"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchResults(t *testing.T) {
+	require := require.New(t)
+
+	var interrupted EventRecords
+	interrupted.Utility_BatchInterrupted = []types.EventUtilityBatchInterrupted{
+		{Index: 1, DispatchError: types.DispatchError{HasModule: true, Module: 9, Error: 21}},
+	}
+
+	var receipt Receipt
+	receipt.ordered = []eventRecord{
+		{Event: EventRecords{Balances_Withdraw: []BalancesWithdraw{{}}}},
+		{Event: EventRecords{TfgridModule_TwinStored: []TwinStored{{Twin: Twin{ID: 1}}}}},
+		{Event: EventRecords{Utility_ItemCompleted: []UtilityItemCompleted{{}}}},
+		{Event: interrupted},
+	}
+
+	results := batchResults(nil, &receipt, 3)
+	require.Len(results, 3)
+	require.NoError(results[0].Error)
+	require.Len(results[0].Events.TfgridModule_TwinStored, 1)
+	require.Empty(results[0].Events.Balances_Withdraw)
+
+	var de *DispatchError
+	require.True(errors.As(results[1].Error, &de))
+	require.EqualValues(9, de.Module)
+	require.EqualValues(21, de.Index)
+	require.ErrorIs(results[2].Error, ErrNotExecuted)
+
+	// a failed batch_all reverts all calls
+	receipt = Receipt{Error: ErrNodeHasActiveContracts}
+	results = batchResults(nil, &receipt, 2)
+	for _, result := range results {
+		require.ErrorIs(result.Error, ErrNodeHasActiveContracts)
+	}
+}
@@ -0,0 +1,509 @@
+package substrate
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/centrifuge/go-substrate-rpc-client/v4/types"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/crypto/blake2b"
+)
+
+var (
+	// auraEngineID is the consensus engine id of aura pre runtime digests
+	auraEngineID = types.ConsensusEngineID(binary.LittleEndian.Uint32([]byte("aura")))
+)
+
+// BlockInfo is a decoded block
+type BlockInfo struct {
+	Number uint32
+	Hash   types.Hash
+	Parent types.Hash
+	// Timestamp is set by the Timestamp.set inherent
+	Timestamp time.Time
+	// Author is the SS58 address of the block author, empty if
+	// it can't be found
+	Author     string
+	Extrinsics []ExtrinsicInfo
+	Events     *EventRecords
+}
+
+// ExtrinsicInfo is a decoded extrinsic of a block
+type ExtrinsicInfo struct {
+	Index uint32
+	Hash  types.Hash
+	// Pallet and Call are the names of the called function
+	Pallet string
+	Call   string
+	// Signer is the SS58 address of the signer, empty for unsigned extrinsics
+	Signer string
+	Nonce  uint64
+	Args   []CallArg
+	// Success is false if the extrinsic failed with Error
+	Success bool
+	Error   *DispatchError
+	// Events emitted by this extrinsic
+	Events EventRecords
+	Raw    types.Extrinsic
+	// DecodeError is set if the call couldn't be decoded with the metadata
+	// of the block, the call fields are then incomplete but Raw is still set
+	DecodeError error
+}
+
+// CallArg is a decoded call argument. Values are decoded using the type
+// information in the metadata:
+//   - composites are map[string]interface{}, or []interface{} if the fields have no names
+//   - enums are the variant name if the variant has no fields, otherwise a map
+//     of the variant name to its fields
+//   - options are nil or the value
+//   - sequences and arrays of bytes are []byte, other sequences are []interface{}
+//   - account ids are SS58 addresses
+//   - compact integers and integers bigger than 64 bits are *big.Int
+type CallArg struct {
+	Name  string
+	Type  string
+	Value interface{}
+}
+
+// GetBlockInfo gets the decoded block with the given hash
+func (s *Substrate) GetBlockInfo(hash types.Hash) (*BlockInfo, error) {
+	cl, _, err := s.getClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return blockInfo(cl, s.metas, hash)
+}
+
+// GetBlockInfoAt gets the decoded block with the given number
+func (s *Substrate) GetBlockInfoAt(number uint32) (*BlockInfo, error) {
+	cl, _, err := s.getClient()
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := cl.RPC.Chain.GetBlockHash(uint64(number))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get hash of block '%d'", number)
+	}
+
+	return blockInfo(cl, s.metas, hash)
+}
+
+// IterateBlocks calls fn with the decoded blocks in [from, to] in order. It
+// stops at the first error returned by fn.
+func (s *Substrate) IterateBlocks(from, to uint32, fn func(block *BlockInfo) error) error {
+	cl, _, err := s.getClient()
+	if err != nil {
+		return err
+	}
+
+	cache := s.metas
+	for number := from; number <= to; number++ {
+		hash, err := cl.RPC.Chain.GetBlockHash(uint64(number))
+		if err != nil {
+			return errors.Wrapf(err, "failed to get hash of block '%d'", number)
+		}
+
+		block, err := blockInfo(cl, cache, hash)
+		if err != nil {
+			return err
+		}
+
+		if err := fn(block); err != nil {
+			return err
+		}
+
+		if number == to {
+			// to is the max uint32
+			break
+		}
+	}
+
+	return nil
+}
+
+func blockInfo(cl Conn, cache *metaCache, hash types.Hash) (*BlockInfo, error) {
+	block, err := cl.RPC.Chain.GetBlock(hash)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get block '%s'", hash.Hex())
+	}
+
+	meta, err := cache.at(cl, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := eventsAt(cl, cache, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	header := &block.Block.Header
+	info := BlockInfo{
+		Number: uint32(header.Number),
+		Hash:   hash,
+		Parent: header.ParentHash,
+		Events: events,
+	}
+
+	info.Author, err = blockAuthor(cl, meta, header)
+	if err != nil {
+		log.Warn().Err(err).Str("block", hash.Hex()).Msg("failed to get block author")
+	}
+
+	timestamp, err := meta.FindCallIndex("Timestamp.set")
+	if err != nil {
+		return nil, err
+	}
+
+	for i, ext := range block.Block.Extrinsics {
+		xt := extrinsicInfo(meta, uint32(i), ext, events)
+		if xt.DecodeError != nil {
+			log.Debug().Err(xt.DecodeError).Str("block", hash.Hex()).Int("index", i).Msg("failed to decode extrinsic")
+		}
+
+		if ext.Method.CallIndex == timestamp {
+			var now types.UCompact
+			if err := types.DecodeFromBytes(ext.Method.Args, &now); err != nil {
+				return nil, errors.Wrap(err, "failed to decode block timestamp")
+			}
+
+			ms := big.Int(now)
+			info.Timestamp = time.Unix(0, ms.Int64()*int64(time.Millisecond))
+		}
+
+		info.Extrinsics = append(info.Extrinsics, xt)
+	}
+
+	return &info, nil
+}
+
+// extrinsicInfo decodes the extrinsic, a failure to decode the call is
+// set in DecodeError so a single bad extrinsic doesn't fail the block
+func extrinsicInfo(meta Meta, index uint32, ext types.Extrinsic, events *EventRecords) ExtrinsicInfo {
+	info := ExtrinsicInfo{
+		Index:  index,
+		Events: filterEvents(events, index),
+		Raw:    ext,
+	}
+
+	encoded, err := types.EncodeToBytes(ext)
+	if err != nil {
+		info.DecodeError = errors.Wrap(err, "failed to encode extrinsic")
+	} else {
+		info.Hash = blake2b.Sum256(encoded)
+	}
+
+	if ext.IsSigned() {
+		signer := ext.Signature.Signer
+		if signer.IsID {
+			info.Signer = AccountID(signer.AsID).String()
+		}
+
+		nonce := big.Int(ext.Signature.Nonce)
+		info.Nonce = nonce.Uint64()
+	}
+
+	info.Error = extrinsicError(meta, &info.Events)
+	info.Success = info.Error == nil
+
+	if meta.Version != 14 {
+		// call names and types are only available in the V14 metadata
+		return info
+	}
+
+	reader := &typeReader{meta: &meta.AsMetadataV14, data: ext.Method.Args}
+	pallet, call, err := reader.variant(ext.Method.CallIndex.SectionIndex, ext.Method.CallIndex.MethodIndex, palletCalls)
+	if err != nil {
+		info.DecodeError = errors.Wrap(err, "call")
+		return info
+	}
+
+	info.Pallet = string(pallet.Name)
+	info.Call = string(call.Name)
+
+	for _, field := range call.Fields {
+		value, err := reader.value(field.Type.Int64())
+		if err != nil {
+			info.DecodeError = errors.Wrapf(err, "failed to decode argument '%s' of %s.%s", field.Name, info.Pallet, info.Call)
+			return info
+		}
+
+		info.Args = append(info.Args, CallArg{
+			Name:  string(field.Name),
+			Type:  string(field.TypeName),
+			Value: value,
+		})
+	}
+
+	return info
+}
+
+// blockAuthor finds the author from the aura slot of the block, the author is
+// the validator at index slot % validators
+func blockAuthor(cl Conn, meta Meta, header *types.Header) (string, error) {
+	var slot uint64
+	found := false
+	for _, digest := range header.Digest {
+		if digest.IsPreRuntime && digest.AsPreRuntime.ConsensusEngineID == auraEngineID {
+			if err := types.DecodeFromBytes(digest.AsPreRuntime.Bytes, &slot); err != nil {
+				return "", errors.Wrap(err, "failed to decode aura slot")
+			}
+			found = true
+		}
+	}
+
+	if !found {
+		return "", nil
+	}
+
+	// the validators that could author the block are the ones set at the parent
+	validators, err := sessionValidators(cl, meta, header.ParentHash)
+	if err != nil {
+		return "", err
+	}
+
+	if len(validators) == 0 {
+		return "", fmt.Errorf("no validators found")
+	}
+
+	return validators[slot%uint64(len(validators))].String(), nil
+}
+
+// sessionValidators gets the validators at the given block, falling back to
+// the aura authorities if the runtime has no session pallet
+func sessionValidators(cl Conn, meta Meta, block types.Hash) ([]AccountID, error) {
+	var validators []AccountID
+	for _, entry := range [][2]string{{"Session", "Validators"}, {"Aura", "Authorities"}} {
+		key, err := types.CreateStorageKey(meta, entry[0], entry[1], nil)
+		if err != nil {
+			continue
+		}
+
+		ok, err := cl.RPC.State.GetStorage(key, &validators, block)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get %s.%s", entry[0], entry[1])
+		}
+
+		if ok {
+			return validators, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// value reads a value of the given type, see CallArg for how values
+// are represented
+func (r *typeReader) value(id int64) (interface{}, error) {
+	typ, ok := r.meta.EfficientLookup[id]
+	if !ok {
+		return nil, fmt.Errorf("type %d not found in metadata", id)
+	}
+
+	def := &typ.Def
+	switch {
+	case def.IsComposite:
+		if len(typ.Path) > 0 && typ.Path[len(typ.Path)-1] == "AccountId32" {
+			data, err := r.take(32)
+			if err != nil {
+				return nil, err
+			}
+			var account AccountID
+			copy(account[:], data)
+			return account.String(), nil
+		}
+
+		return r.fields(def.Composite.Fields)
+	case def.IsVariant:
+		index, err := r.readByte()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, variant := range def.Variant.Variants {
+			if uint8(variant.Index) != index {
+				continue
+			}
+
+			if len(variant.Fields) == 0 {
+				if variant.Name == "None" {
+					return nil, nil
+				}
+				return string(variant.Name), nil
+			}
+
+			value, err := r.fields(variant.Fields)
+			if err != nil {
+				return nil, err
+			}
+
+			if variant.Name == "Some" {
+				return value, nil
+			}
+
+			return map[string]interface{}{string(variant.Name): value}, nil
+		}
+
+		return nil, fmt.Errorf("variant %d of type %d not found", index, id)
+	case def.IsSequence:
+		count, err := r.compact()
+		if err != nil {
+			return nil, err
+		}
+
+		return r.values(def.Sequence.Type.Int64(), count)
+	case def.IsArray:
+		return r.values(def.Array.Type.Int64(), uint64(def.Array.Len))
+	case def.IsTuple:
+		if len(def.Tuple) == 0 {
+			return nil, nil
+		}
+
+		values := make([]interface{}, 0, len(def.Tuple))
+		for _, elem := range def.Tuple {
+			value, err := r.value(elem.Int64())
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+
+		return values, nil
+	case def.IsPrimitive:
+		return r.primitiveValue(def.Primitive.Si0TypeDefPrimitive)
+	case def.IsCompact:
+		return r.compactBig()
+	case def.IsBitSequence:
+		start := r.pos
+		if err := r.skip(id); err != nil {
+			return nil, err
+		}
+
+		return append([]byte(nil), r.data[start:r.pos]...), nil
+	default:
+		return nil, fmt.Errorf("unsupported type %d", id)
+	}
+}
+
+// fields reads the fields of a composite or a variant. A single unnamed
+// field is returned as is.
+func (r *typeReader) fields(fields []types.Si1Field) (interface{}, error) {
+	if len(fields) == 1 && !fields[0].HasName {
+		return r.value(fields[0].Type.Int64())
+	}
+
+	if len(fields) > 0 && !fields[0].HasName {
+		values := make([]interface{}, 0, len(fields))
+		for _, field := range fields {
+			value, err := r.value(field.Type.Int64())
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+
+		return values, nil
+	}
+
+	values := make(map[string]interface{}, len(fields))
+	for _, field := range fields {
+		value, err := r.value(field.Type.Int64())
+		if err != nil {
+			return nil, err
+		}
+		values[string(field.Name)] = value
+	}
+
+	return values, nil
+}
+
+// values reads count values of the given type
+func (r *typeReader) values(id int64, count uint64) (interface{}, error) {
+	if typ, ok := r.meta.EfficientLookup[id]; ok && typ.Def.IsPrimitive && typ.Def.Primitive.Si0TypeDefPrimitive == types.IsU8 {
+		data, err := r.take(int(count))
+		if err != nil {
+			return nil, err
+		}
+
+		return append([]byte(nil), data...), nil
+	}
+
+	// the count is read from the data, so it's checked before allocating
+	if err := r.fits(count); err != nil {
+		return nil, err
+	}
+
+	values := make([]interface{}, 0, count)
+	for i := uint64(0); i < count; i++ {
+		value, err := r.value(id)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+
+	return values, nil
+}
+
+func (r *typeReader) primitiveValue(primitive types.Si0TypeDefPrimitive) (interface{}, error) {
+	if primitive == types.IsStr {
+		size, err := r.compact()
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := r.take(int(size))
+		if err != nil {
+			return nil, err
+		}
+
+		return string(data), nil
+	}
+
+	size, ok := fixedSize(primitive)
+	if !ok {
+		return nil, fmt.Errorf("unknown primitive %d", primitive)
+	}
+
+	data, err := r.take(size)
+	if err != nil {
+		return nil, err
+	}
+
+	switch primitive {
+	case types.IsBool:
+		return data[0] != 0, nil
+	case types.IsChar:
+		return rune(binary.LittleEndian.Uint32(data)), nil
+	case types.IsU8:
+		return data[0], nil
+	case types.IsU16:
+		return binary.LittleEndian.Uint16(data), nil
+	case types.IsU32:
+		return binary.LittleEndian.Uint32(data), nil
+	case types.IsU64:
+		return binary.LittleEndian.Uint64(data), nil
+	case types.IsI8:
+		return int8(data[0]), nil
+	case types.IsI16:
+		return int16(binary.LittleEndian.Uint16(data)), nil
+	case types.IsI32:
+		return int32(binary.LittleEndian.Uint32(data)), nil
+	case types.IsI64:
+		return int64(binary.LittleEndian.Uint64(data)), nil
+	case types.IsU128, types.IsU256:
+		return littleEndian(data), nil
+	default:
+		// signed big integers in two's complement
+		value := littleEndian(data)
+		if data[len(data)-1]&0x80 != 0 {
+			value.Sub(value, big.NewInt(0).Lsh(big.NewInt(1), uint(size*8)))
+		}
+		return value, nil
+	}
+}
@@ -0,0 +1,91 @@
+package substrate
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/centrifuge/go-substrate-rpc-client/v4/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtrinsicInfo(t *testing.T) {
+	require := require.New(t)
+
+	account := compositeType(3)
+	account.Path = types.Si1Path{"sp_core", "crypto", "AccountId32"}
+
+	meta := testMetadata(t, map[int64]*types.Si1Type{
+		1: variantType(types.Si1Variant{
+			Name:  "transfer",
+			Index: 2,
+			Fields: []types.Si1Field{
+				{HasName: true, Name: "dest", Type: typeID(2), HasTypeName: true, TypeName: "AccountId"},
+				{HasName: true, Name: "value", Type: typeID(5)},
+				{HasName: true, Name: "memo", Type: typeID(6)},
+				{HasName: true, Name: "limit", Type: typeID(8)},
+			},
+		}),
+		2: account,
+		3: {Def: types.Si1TypeDef{IsArray: true, Array: types.Si1TypeDefArray{Len: 32, Type: typeID(4)}}},
+		4: primitiveType(types.IsU8),
+		5: {Def: types.Si1TypeDef{IsCompact: true, Compact: types.Si1TypeDefCompact{Type: typeID(7)}}},
+		6: {Def: types.Si1TypeDef{IsSequence: true, Sequence: types.Si1TypeDefSequence{Type: typeID(4)}}},
+		7: primitiveType(types.IsU128),
+		8: optionType(9),
+		9: primitiveType(types.IsU32),
+	}, testPallet{name: "TfgridModule", index: 11, calls: 1})
+
+	dest := AccountID{1, 2, 3}
+	args := append([]byte{}, dest[:]...)
+	args = append(args, 0x02, 0x09, 0x3d, 0x00) // compact 1000000
+	args = append(args, 2<<2, 'h', 'i')
+	args = append(args, 1, 10, 0, 0, 0)
+
+	ext := types.Extrinsic{
+		Version: types.ExtrinsicVersion4,
+		Method:  types.Call{CallIndex: types.CallIndex{SectionIndex: 11, MethodIndex: 2}, Args: args},
+	}
+
+	events := &EventRecords{}
+	events.System_ExtrinsicFailed = []types.EventSystemExtrinsicFailed{
+		{
+			Phase:         types.Phase{IsApplyExtrinsic: true, AsApplyExtrinsic: 1},
+			DispatchError: types.DispatchError{HasModule: true, Module: 11, Error: 0},
+		},
+	}
+
+	info := extrinsicInfo(meta, 1, ext, events)
+	require.NoError(info.DecodeError)
+	require.Equal("TfgridModule", info.Pallet)
+	require.Equal("transfer", info.Call)
+	require.Empty(info.Signer)
+	require.False(info.Success)
+	require.NotNil(info.Error)
+	require.Len(info.Events.System_ExtrinsicFailed, 1)
+
+	require.Len(info.Args, 4)
+	require.Equal(CallArg{Name: "dest", Type: "AccountId", Value: dest.String()}, info.Args[0])
+	require.Equal(big.NewInt(1000000), info.Args[1].Value)
+	require.Equal([]byte("hi"), info.Args[2].Value)
+	require.Equal(uint32(10), info.Args[3].Value)
+
+	// same extrinsic in another position has no events
+	info = extrinsicInfo(meta, 0, ext, events)
+	require.NoError(info.DecodeError)
+	require.True(info.Success)
+
+	// a sequence length read from bad data doesn't allocate, and the
+	// extrinsic is kept with the decode error
+	bad := ext
+	bad.Method.Args = append(append([]byte{}, dest[:]...), 0x02, 0x09, 0x3d, 0x00, 0x13, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff)
+	info = extrinsicInfo(meta, 0, bad, events)
+	require.Error(info.DecodeError)
+	require.Equal("transfer", info.Call)
+	require.Equal(bad, info.Raw)
+	require.NotEqual(types.Hash{}, info.Hash)
+
+	meta.AsMetadataV14.EfficientLookup[6].Def.Sequence.Type = typeID(9)
+	info = extrinsicInfo(meta, 0, bad, events)
+	require.Error(info.DecodeError)
+	require.Contains(info.DecodeError.Error(), "don't fit")
+}
@@ -0,0 +1,162 @@
+package substrate
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// timeCacheSize is the max number of block timestamps kept in the cache
+	timeCacheSize = 10000
+)
+
+// timeCache keeps the timestamps of blocks by height. Only heights at or
+// below the finalized head are cached, so a height always refers to the same
+// block and entries never expire, the cache is simply reset once full.
+type timeCache struct {
+	m     sync.Mutex
+	times map[uint32]time.Time
+	// finalized is the last known finalized height
+	finalized uint32
+}
+
+func newTimeCache() *timeCache {
+	return &timeCache{times: make(map[uint32]time.Time)}
+}
+
+// final checks if the height is known to be finalized
+func (c *timeCache) final(height uint32) bool {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	return height <= c.finalized
+}
+
+// refresh updates the finalized height from the chain
+func (c *timeCache) refresh(cl Conn) error {
+	hash, err := cl.RPC.Chain.GetFinalizedHead()
+	if err != nil {
+		return errors.Wrap(err, "failed to get finalized head")
+	}
+
+	header, err := cl.RPC.Chain.GetHeader(hash)
+	if err != nil {
+		return errors.Wrap(err, "failed to get finalized header")
+	}
+
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	if uint32(header.Number) > c.finalized {
+		c.finalized = uint32(header.Number)
+	}
+
+	return nil
+}
+
+// at returns the timestamp of the block with the given height, it's only
+// cached if the height is finalized. The finalized height is not refreshed,
+// callers refresh it once before looking up recent blocks.
+func (c *timeCache) at(cl Conn, meta Meta, height uint32) (time.Time, error) {
+	c.m.Lock()
+	t, ok := c.times[height]
+	c.m.Unlock()
+
+	if ok {
+		return t, nil
+	}
+
+	hash, err := cl.RPC.Chain.GetBlockHash(uint64(height))
+	if err != nil {
+		return time.Time{}, errors.Wrapf(err, "failed to get hash of block '%d'", height)
+	}
+
+	t, err = getTimeAt(cl, meta, hash)
+	if err != nil {
+		return t, err
+	}
+
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	if height > c.finalized {
+		return t, nil
+	}
+
+	if len(c.times) >= timeCacheSize {
+		c.times = make(map[uint32]time.Time)
+	}
+	c.times[height] = t
+
+	return t, nil
+}
+
+// BlockTime gets the time of the block with the given height as
+// set by the block author
+func (s *Substrate) BlockTime(height uint32) (time.Time, error) {
+	cl, meta, err := s.getClient()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	cache := s.times
+	if !cache.final(height) {
+		if err := cache.refresh(cl); err != nil {
+			return time.Time{}, err
+		}
+	}
+
+	return cache.at(cl, meta, height)
+}
+
+// BlockAt gets the height of the last block produced at or before t. If t is
+// after the current block, the current height is returned. ErrNotFound is
+// returned if t is before the first block.
+func (s *Substrate) BlockAt(t time.Time) (uint32, error) {
+	cl, meta, err := s.getClient()
+	if err != nil {
+		return 0, err
+	}
+
+	current, err := s.GetCurrentHeight()
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to get current height")
+	}
+
+	cache := s.times
+	if !cache.final(current) {
+		if err := cache.refresh(cl); err != nil {
+			return 0, err
+		}
+	}
+
+	var lookupErr error
+	// find the first block after t, the genesis block has no timestamp
+	// so the search starts from block 1
+	after := sort.Search(int(current), func(i int) bool {
+		if lookupErr != nil {
+			return true
+		}
+
+		blockTime, err := cache.at(cl, meta, uint32(i)+1)
+		if err != nil {
+			lookupErr = err
+			return true
+		}
+
+		return blockTime.After(t)
+	})
+
+	if lookupErr != nil {
+		return 0, lookupErr
+	}
+
+	if after == 0 {
+		return 0, errors.Wrapf(ErrNotFound, "no blocks before '%s'", t)
+	}
+
+	return uint32(after), nil
+}
@@ -1,10 +1,17 @@
 package substrate
 
 import (
+	"fmt"
+
 	"github.com/centrifuge/go-substrate-rpc-client/v4/types"
 	"github.com/pkg/errors"
 )
 
+const (
+	// queryStorageBlocks is the max number of blocks queried at once
+	queryStorageBlocks = 100
+)
+
 func (s *Substrate) GetCurrentHeight() (uint32, error) {
 	cl, meta, err := s.getClient()
 	if err != nil {
@@ -59,43 +66,152 @@ func (s *Substrate) FetchEventsForBlockRange(start uint32, end uint32) (types.St
 	return key, rawSet, nil
 }
 
-func (s *Substrate) GetEventsForBlock(start uint32) (*EventRecords, error) {
+// GetEventsForBlockRange gets the decoded events of all blocks in [start, end].
+// Events of each block are decoded with the metadata of the runtime version
+// of that block, so the range can span runtime upgrades.
+func (s *Substrate) GetEventsForBlockRange(start uint32, end uint32) ([]BlockEvents, error) {
 	cl, _, err := s.getClient()
 	if err != nil {
 		return nil, err
 	}
 
-	block, err := cl.RPC.Chain.GetBlockHash(uint64(start))
+	if start > end {
+		return nil, fmt.Errorf("invalid block range [%d, %d]", start, end)
+	}
+
+	return blockEventsRange(cl, s.metas, start, end)
+}
+
+// blockEventsRange gets the events of all blocks in [from, to]. Storage is
+// queried in chunks so big ranges don't hit the node response limits.
+func blockEventsRange(cl Conn, cache *metaCache, from, to uint32) ([]BlockEvents, error) {
+	blocks := make([]BlockEvents, 0, to-from+1)
+	for start := from; start <= to; start += queryStorageBlocks {
+		end := start + queryStorageBlocks - 1
+		if end > to || end < start {
+			end = to
+		}
+
+		chunk, err := blockEventsChunk(cl, cache, start, end)
+		if err != nil {
+			return nil, err
+		}
+
+		blocks = append(blocks, chunk...)
+		if end == to {
+			break
+		}
+	}
+
+	return blocks, nil
+}
+
+func blockEventsChunk(cl Conn, cache *metaCache, from, to uint32) ([]BlockEvents, error) {
+	fromHash, err := cl.RPC.Chain.GetBlockHash(uint64(from))
 	if err != nil {
-		return nil, err
+		return nil, errors.Wrapf(err, "failed to get hash of block '%d'", from)
+	}
+
+	toHash, err := cl.RPC.Chain.GetBlockHash(uint64(to))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get hash of block '%d'", to)
 	}
-	meta, err := cl.RPC.State.GetMetadata(block)
+
+	fromMeta, err := cache.at(cl, fromHash)
 	if err != nil {
 		return nil, err
 	}
 
-	key, err := types.CreateStorageKey(meta, "System", "Events", nil)
+	toMeta, err := cache.at(cl, toHash)
 	if err != nil {
 		return nil, err
 	}
 
-	var storageData types.StorageDataRaw
-	ok, err := cl.RPC.State.GetStorage(key, &storageData, block)
+	// the key of the events is the same for all runtime versions
+	key, err := types.CreateStorageKey(fromMeta, "System", "Events", nil)
 	if err != nil {
 		return nil, err
 	}
 
-	if !ok {
-		return nil, errors.New("failed to get storage")
+	sets, err := cl.RPC.State.QueryStorage([]types.StorageKey{key}, fromHash, toHash)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query events storage")
+	}
+
+	changes := make(map[types.Hash]types.StorageDataRaw, len(sets))
+	for _, set := range sets {
+		var raw types.StorageDataRaw
+		for _, change := range set.Changes {
+			if change.HasStorageData {
+				raw = change.StorageData
+			}
+		}
+		changes[set.Block] = raw
+	}
+
+	var (
+		blocks = make([]BlockEvents, 0, to-from+1)
+		raw    types.StorageDataRaw
+	)
+
+	for number := from; number <= to; number++ {
+		hash := fromHash
+		if number == to {
+			hash = toHash
+		} else if len(sets) == cap(blocks) {
+			// the events changed in every block so the sets
+			// already have the hashes of all blocks in order
+			hash = sets[number-from].Block
+		} else if number != from {
+			hash, err = cl.RPC.Chain.GetBlockHash(uint64(number))
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to get hash of block '%d'", number)
+			}
+		}
+
+		if change, ok := changes[hash]; ok {
+			raw = change
+		} else if number == from {
+			data, err := cl.RPC.State.GetStorageRaw(key, hash)
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to get block events")
+			}
+			raw = *data
+		}
+		// otherwise the events didn't change since the previous block
+
+		meta := fromMeta
+		if fromMeta != toMeta {
+			// a runtime upgrade happened in this chunk
+			meta, err = cache.at(cl, hash)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		events, err := decodeEvents(meta, hash, raw)
+		if err != nil {
+			return nil, err
+		}
+
+		blocks = append(blocks, BlockEvents{Number: number, Hash: hash, Events: events})
+	}
+
+	return blocks, nil
+}
+
+func (s *Substrate) GetEventsForBlock(start uint32) (*EventRecords, error) {
+	cl, _, err := s.getClient()
+	if err != nil {
+		return nil, err
 	}
 
-	events := EventRecords{}
-	err = types.EventRecordsRaw(storageData).DecodeEventRecords(meta, &events)
+	block, err := cl.RPC.Chain.GetBlockHash(uint64(start))
 	if err != nil {
 		return nil, err
 	}
 
-	return &events, nil
+	return eventsAt(cl, s.metas, block)
 }
 
 func (s *Substrate) GetBlock(block types.Hash) (*types.SignedBlock, error) {
@@ -0,0 +1,177 @@
+// scalecapture captures the golden scale fixtures of the substrate package
+// from the storage of a running chain, so the types are checked against
+// the bytes written by the runtime. Run it from the repository root:
+//
+//	go run ./cmd/scalecapture -url wss://tfchain.dev.grid.tf
+//
+// Every fixture starts with the chain, block hash and storage key the
+// value was read from.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/centrifuge/go-substrate-rpc-client/v4/types"
+	"github.com/centrifuge/go-substrate-rpc-client/v4/xxhash"
+	"github.com/pkg/errors"
+	substrate "github.com/threefoldtech/substrate-client"
+)
+
+const (
+	page = 100
+	// maxKeys bounds the keys scanned looking for a matching value
+	maxKeys = 10000
+)
+
+// fixture is a golden fixture and the storage map its value is read from
+type fixture struct {
+	name    string
+	pallet  string
+	storage string
+	// match selects the value to capture, the first value is
+	// captured if it's nil
+	match func(data []byte) bool
+}
+
+// contractOf matches the contracts of the given type
+func contractOf(is func(typ substrate.ContractType) bool) func(data []byte) bool {
+	return func(data []byte) bool {
+		var contract substrate.Contract
+		if err := types.DecodeFromBytes(data, &contract); err != nil {
+			return false
+		}
+
+		return is(contract.ContractType)
+	}
+}
+
+var fixtures = []fixture{
+	{name: "twin", pallet: "TfgridModule", storage: "Twins"},
+	{name: "farm", pallet: "TfgridModule", storage: "Farms"},
+	{name: "node", pallet: "TfgridModule", storage: "Nodes"},
+	{name: "entity", pallet: "TfgridModule", storage: "Entities"},
+	{name: "pricing_policy", pallet: "TfgridModule", storage: "PricingPolicies"},
+	{name: "farming_policy", pallet: "TfgridModule", storage: "FarmingPoliciesMap"},
+	{name: "validator", pallet: "Validator", storage: "Validator"},
+	{
+		name: "node_contract", pallet: "SmartContractModule", storage: "Contracts",
+		match: contractOf(func(typ substrate.ContractType) bool { return typ.IsNodeContract }),
+	},
+	{
+		name: "name_contract", pallet: "SmartContractModule", storage: "Contracts",
+		match: contractOf(func(typ substrate.ContractType) bool { return typ.IsNameContract }),
+	},
+	{
+		name: "rent_contract", pallet: "SmartContractModule", storage: "Contracts",
+		match: contractOf(func(typ substrate.ContractType) bool { return typ.IsRentContract }),
+	},
+}
+
+// prefix returns the key prefix of all the entries of a storage map
+func prefix(pallet, storage string) types.StorageKey {
+	key := xxhash.New128([]byte(pallet)).Sum(nil)
+	return append(key, xxhash.New128([]byte(storage)).Sum(nil)...)
+}
+
+// capture returns the first value of the fixture storage map that matches
+func capture(cl substrate.Conn, meta substrate.Meta, block types.Hash, f fixture) (types.StorageKey, []byte, error) {
+	if _, err := meta.FindStorageEntryMetadata(f.pallet, f.storage); err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to find %s.%s", f.pallet, f.storage)
+	}
+
+	prefix := prefix(f.pallet, f.storage)
+	var start *string
+	for scanned := 0; scanned < maxKeys; {
+		var keys []string
+		if err := cl.Client.Call(&keys, "state_getKeysPaged", prefix.Hex(), page, start, block.Hex()); err != nil {
+			return nil, nil, errors.Wrap(err, "failed to list storage keys")
+		}
+
+		for _, hex := range keys {
+			key, err := types.HexDecodeString(hex)
+			if err != nil {
+				return nil, nil, errors.Wrapf(err, "invalid storage key '%s'", hex)
+			}
+
+			raw, err := cl.RPC.State.GetStorageRaw(key, block)
+			if err != nil {
+				return nil, nil, errors.Wrapf(err, "failed to get storage '%s'", hex)
+			}
+
+			if f.match == nil || f.match(*raw) {
+				return types.StorageKey(key), *raw, nil
+			}
+		}
+
+		if len(keys) < page {
+			break
+		}
+
+		scanned += len(keys)
+		start = &keys[len(keys)-1]
+	}
+
+	return nil, nil, fmt.Errorf("no matching value in %s.%s", f.pallet, f.storage)
+}
+
+func main() {
+	url := flag.String("url", "wss://tfchain.dev.grid.tf", "url of the chain to capture the fixtures from")
+	dir := flag.String("dir", "testdata/scale", "directory to write the fixtures to")
+	flag.Parse()
+
+	if err := os.MkdirAll(*dir, 0755); err != nil {
+		log.Fatal(err)
+	}
+
+	mgr := substrate.NewManager(*url)
+	defer mgr.Close()
+
+	cl, meta, err := mgr.Raw()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	chain, err := cl.RPC.System.Chain()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// all fixtures are captured at the same finalized block
+	block, err := cl.RPC.Chain.GetFinalizedHead()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	version, err := cl.RPC.State.GetRuntimeVersion(block)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, f := range fixtures {
+		key, data, err := capture(cl, meta, block, f)
+		if err != nil {
+			log.Fatalf("%s: %s", f.name, err)
+		}
+
+		var buf bytes.Buffer
+		fmt.Fprintf(&buf, "# chain: %s\n", chain)
+		fmt.Fprintf(&buf, "# source: %s\n", *url)
+		fmt.Fprintf(&buf, "# block: %s\n", block.Hex())
+		fmt.Fprintf(&buf, "# spec: %s %d\n", version.SpecName, version.SpecVersion)
+		fmt.Fprintf(&buf, "# key: %s\n", key.Hex())
+		fmt.Fprintf(&buf, "%#x\n", data)
+
+		path := filepath.Join(*dir, fmt.Sprintf("%s.hex", f.name))
+		if err := ioutil.WriteFile(path, buf.Bytes(), 0644); err != nil {
+			log.Fatal(err)
+		}
+
+		log.Printf("captured %s from %s", f.name, key.Hex())
+	}
+}
@@ -0,0 +1,104 @@
+package substrate
+
+import (
+	"context"
+
+	gsrpc "github.com/centrifuge/go-substrate-rpc-client/v4"
+	"github.com/centrifuge/go-substrate-rpc-client/v4/client"
+	"github.com/centrifuge/go-substrate-rpc-client/v4/config"
+	gethrpc "github.com/centrifuge/go-substrate-rpc-client/v4/gethrpc"
+	"github.com/centrifuge/go-substrate-rpc-client/v4/rpc"
+	"github.com/centrifuge/go-substrate-rpc-client/v4/rpc/author"
+	"github.com/centrifuge/go-substrate-rpc-client/v4/rpc/chain"
+	"github.com/centrifuge/go-substrate-rpc-client/v4/rpc/offchain"
+	"github.com/centrifuge/go-substrate-rpc-client/v4/rpc/state"
+	"github.com/centrifuge/go-substrate-rpc-client/v4/rpc/system"
+)
+
+// rpcClient implements client.Client on top of a raw rpc client
+type rpcClient struct {
+	*gethrpc.Client
+	url string
+}
+
+// URL implements client.Client
+func (c *rpcClient) URL() string {
+	return c.url
+}
+
+// ctxClient binds all calls made over the underlying client to ctx
+type ctxClient struct {
+	client.Client
+	ctx context.Context
+}
+
+// Call implements client.Client
+func (c *ctxClient) Call(result interface{}, method string, args ...interface{}) error {
+	type callContext interface {
+		CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error
+	}
+
+	if cl, ok := c.Client.(callContext); ok {
+		return cl.CallContext(c.ctx, result, method, args...)
+	}
+
+	if err := c.ctx.Err(); err != nil {
+		return err
+	}
+
+	// the client does not support contexts, so we can only
+	// stop waiting for the result
+	ch := make(chan error, 1)
+	go func() {
+		ch <- c.Client.Call(result, method, args...)
+	}()
+
+	select {
+	case <-c.ctx.Done():
+		return c.ctx.Err()
+	case err := <-ch:
+		return err
+	}
+}
+
+// dial opens a new rpc client to the given endpoint
+func dial(ctx context.Context, endpoint string) (client.Client, error) {
+	cl, err := gethrpc.DialContext(ctx, endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	return &rpcClient{Client: cl, url: endpoint}, nil
+}
+
+// newConn wraps the client with the substrate rpc api. Unlike gsrpc.NewSubstrateAPI
+// this does not fetch the metadata.
+func newConn(cl client.Client) Conn {
+	return &gsrpc.SubstrateAPI{
+		RPC: &rpc.RPC{
+			Author:   author.NewAuthor(cl),
+			Chain:    chain.NewChain(cl),
+			Offchain: offchain.NewOffchain(cl),
+			State:    state.NewState(cl),
+			System:   system.NewSystem(cl),
+		},
+		Client: cl,
+	}
+}
+
+// withContext returns a view of the connection where all calls are bound to ctx.
+// The returned connection must not be closed, close the original one instead.
+func withContext(ctx context.Context, cl Conn) Conn {
+	return newConn(&ctxClient{Client: cl.Client, ctx: ctx})
+}
+
+// subscribe subscribes to the notifications of the rpc method namespace_method
+// on ch. Unlike the gsrpc subscription wrappers the channel is never closed,
+// they close it on Unsubscribe while a notification may still be delivered on
+// it, which panics.
+func subscribe(cl Conn, ch interface{}, namespace, method, unsubscribe, notification string, args ...interface{}) (*gethrpc.ClientSubscription, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), config.Default().SubscribeTimeout)
+	defer cancel()
+
+	return cl.Client.Subscribe(ctx, namespace, method, unsubscribe, notification, ch, args...)
+}
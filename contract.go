@@ -9,8 +9,9 @@ import (
 )
 
 type DeletedState struct {
-	IsCanceledByUser bool
-	IsOutOfFunds     bool
+	IsCanceledByUser       bool
+	IsOutOfFunds           bool
+	IsCanceledByCollective bool
 }
 
 // Decode implementation for the enum type
@@ -26,6 +27,7 @@ func (r *DeletedState) Decode(decoder scale.Decoder) error {
 	case 1:
 		r.IsOutOfFunds = true
 	case 2:
+		r.IsCanceledByCollective = true
 	default:
 		return fmt.Errorf("unknown deleted state value")
 	}
@@ -39,6 +41,8 @@ func (r DeletedState) Encode(encoder scale.Encoder) (err error) {
 		err = encoder.PushByte(0)
 	} else if r.IsOutOfFunds {
 		err = encoder.PushByte(1)
+	} else if r.IsCanceledByCollective {
+		err = encoder.PushByte(2)
 	}
 	return
 }
@@ -207,13 +211,13 @@ func (s *Substrate) CreateNodeContract(identity Identity, node uint32, body []by
 		return 0, errors.Wrap(err, "failed to create call")
 	}
 
-	blockHash, err := s.Call(cl, meta, identity, c)
+	receipt, err := s.Submit(cl, meta, identity, c)
 	if err != nil {
 		return 0, errors.Wrap(err, "failed to create contract")
 	}
 
-	if err := s.checkForError(cl, meta, blockHash, types.NewAccountID(identity.PublicKey())); err != nil {
-		return 0, err
+	if id, ok := createdContract(&receipt); ok {
+		return id, nil
 	}
 
 	return s.GetContractWithHash(node, hash)
@@ -234,18 +238,27 @@ func (s *Substrate) CreateNameContract(identity Identity, name string) (uint64,
 		return 0, errors.Wrap(err, "failed to create call")
 	}
 
-	blockHash, err := s.Call(cl, meta, identity, c)
+	receipt, err := s.Submit(cl, meta, identity, c)
 	if err != nil {
 		return 0, errors.Wrap(err, "failed to create contract")
 	}
 
-	if err := s.checkForError(cl, meta, blockHash, types.NewAccountID(identity.PublicKey())); err != nil {
-		return 0, err
+	if id, ok := createdContract(&receipt); ok {
+		return id, nil
 	}
 
 	return s.GetContractIDByNameRegistration(name)
 }
 
+// createdContract gets the id of the contract created by the extrinsic
+func createdContract(receipt *Receipt) (uint64, bool) {
+	if events := receipt.Events.SmartContractModule_ContractCreated; len(events) > 0 {
+		return uint64(events[0].Contract.ContractID), true
+	}
+
+	return 0, false
+}
+
 // CreateRentContract creates a rent contract on a node
 func (s *Substrate) CreateRentContract(identity Identity, node uint32) (uint64, error) {
 	cl, meta, err := s.getClient()
@@ -261,17 +274,16 @@ func (s *Substrate) CreateRentContract(identity Identity, node uint32) (uint64,
 		return 0, errors.Wrap(err, "failed to create call")
 	}
 
-	blockHash, err := s.Call(cl, meta, identity, c)
+	receipt, err := s.Submit(cl, meta, identity, c)
 	if err != nil {
 		return 0, errors.Wrap(err, "failed to create rent contract")
 	}
 
-	if err := s.checkForError(cl, meta, blockHash, types.NewAccountID(identity.PublicKey())); err != nil {
-		return 0, err
+	if id, ok := createdContract(&receipt); ok {
+		return id, nil
 	}
 
-	// TODO, how do I get the ID here?
-	return 0, nil
+	return s.GetNodeRentContract(node)
 }
 
 // UpdateNodeContract updates existing contract
@@ -289,15 +301,10 @@ func (s *Substrate) UpdateNodeContract(identity Identity, contract uint64, body
 		return 0, errors.Wrap(err, "failed to create call")
 	}
 
-	blockHash, err := s.Call(cl, meta, identity, c)
-	if err != nil {
+	if _, err := s.Call(cl, meta, identity, c); err != nil {
 		return 0, errors.Wrap(err, "failed to update contract")
 	}
 
-	if err := s.checkForError(cl, meta, blockHash, types.NewAccountID(identity.PublicKey())); err != nil {
-		return 0, err
-	}
-
 	return contract, nil
 }
 
@@ -314,15 +321,10 @@ func (s *Substrate) CancelContract(identity Identity, contract uint64) error {
 		return errors.Wrap(err, "failed to cancel call")
 	}
 
-	blockHash, err := s.Call(cl, meta, identity, c)
-	if err != nil {
+	if _, err := s.Call(cl, meta, identity, c); err != nil {
 		return errors.Wrap(err, "failed to cancel contract")
 	}
 
-	if err := s.checkForError(cl, meta, blockHash, types.NewAccountID(identity.PublicKey())); err != nil {
-		return err
-	}
-
 	return nil
 }
 
@@ -342,15 +344,10 @@ func (s *Substrate) SetContractConsumption(identity Identity, resources ...Contr
 		return errors.Wrap(err, "failed to create call")
 	}
 
-	blockHash, err := s.Call(cl, meta, identity, c)
-	if err != nil {
+	if _, err := s.Call(cl, meta, identity, c); err != nil {
 		return errors.Wrap(err, "failed to set contract used resources")
 	}
 
-	if err := s.checkForError(cl, meta, blockHash, types.NewAccountID(identity.PublicKey())); err != nil {
-		return err
-	}
-
 	return nil
 }
 
@@ -406,6 +403,34 @@ func (s *Substrate) GetContractWithHash(node uint32, hash string) (uint64, error
 	return uint64(contract), nil
 }
 
+// GetNodeRentContract gets the active rent contract on a node
+func (s *Substrate) GetNodeRentContract(node uint32) (uint64, error) {
+	cl, meta, err := s.getClient()
+	if err != nil {
+		return 0, err
+	}
+
+	nodeBytes, err := types.EncodeToBytes(node)
+	if err != nil {
+		return 0, err
+	}
+	key, err := types.CreateStorageKey(meta, "SmartContractModule", "ActiveRentContractForNode", nodeBytes, nil)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to create substrate query key")
+	}
+	var contract types.U64
+	_, err = cl.RPC.State.GetStorageLatest(key, &contract)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to lookup contracts")
+	}
+
+	if contract == 0 {
+		return 0, errors.Wrap(ErrNotFound, "contract not found")
+	}
+
+	return uint64(contract), nil
+}
+
 // GetContractIDByNameRegistration gets a contract given the its name
 func (s *Substrate) GetContractIDByNameRegistration(name string) (uint64, error) {
 	cl, meta, err := s.getClient()
@@ -179,3 +179,170 @@ type ContractGracePeriodEnded struct {
 	TwinID     types.U32
 	Topics     []types.Hash
 }
+
+// Provider is a solution provider account and its share of the billed amount
+type Provider struct {
+	Who  AccountID
+	Take types.U8
+}
+
+// SolutionProvider structure
+type SolutionProvider struct {
+	SolutionProviderID types.U64
+	Providers          []Provider
+	Description        string
+	Link               string
+	Approved           bool
+}
+
+// SolutionProviderCreated
+type SolutionProviderCreated struct {
+	Phase            types.Phase
+	SolutionProvider SolutionProvider
+	Topics           []types.Hash
+}
+
+// SolutionProviderApproved
+type SolutionProviderApproved struct {
+	Phase              types.Phase
+	SolutionProviderID types.U64
+	Approved           bool
+	Topics             []types.Hash
+}
+
+// BillingFrequencyChanged
+type BillingFrequencyChanged struct {
+	Phase     types.Phase
+	Frequency types.U64
+	Topics    []types.Hash
+}
+
+// NodeExtraFeeSet
+type NodeExtraFeeSet struct {
+	Phase    types.Phase
+	NodeID   types.U32
+	ExtraFee types.U64
+	Topics   []types.Hash
+}
+
+// ServiceContractState enum
+type ServiceContractState struct {
+	IsCreated        bool
+	IsAgreementReady bool
+	IsApprovedByBoth bool
+}
+
+// Decode implementation for the enum type
+func (r *ServiceContractState) Decode(decoder scale.Decoder) error {
+	b, err := decoder.ReadOneByte()
+	if err != nil {
+		return err
+	}
+
+	switch b {
+	case 0:
+		r.IsCreated = true
+	case 1:
+		r.IsAgreementReady = true
+	case 2:
+		r.IsApprovedByBoth = true
+	default:
+		return fmt.Errorf("unknown ServiceContractState value")
+	}
+
+	return nil
+}
+
+// Encode implementation
+func (r ServiceContractState) Encode(encoder scale.Encoder) (err error) {
+	if r.IsCreated {
+		err = encoder.PushByte(0)
+	} else if r.IsAgreementReady {
+		err = encoder.PushByte(1)
+	} else if r.IsApprovedByBoth {
+		err = encoder.PushByte(2)
+	}
+
+	return
+}
+
+// ServiceContract structure
+type ServiceContract struct {
+	ServiceContractID  types.U64
+	ServiceTwinID      types.U32
+	ConsumerTwinID     types.U32
+	BaseFee            types.U64
+	VariableFee        types.U64
+	Metadata           string
+	AcceptedByService  bool
+	AcceptedByConsumer bool
+	LastBill           types.U64
+	State              ServiceContractState
+}
+
+// ServiceContractBill structure
+type ServiceContractBill struct {
+	VariableAmount types.U64
+	Window         types.U64
+	Metadata       string
+}
+
+// Cause of a service contract cancellation
+type Cause struct {
+	IsCanceledByUser bool
+	IsOutOfFunds     bool
+}
+
+// Decode implementation for the enum type
+func (r *Cause) Decode(decoder scale.Decoder) error {
+	b, err := decoder.ReadOneByte()
+	if err != nil {
+		return err
+	}
+
+	switch b {
+	case 0:
+		r.IsCanceledByUser = true
+	case 1:
+		r.IsOutOfFunds = true
+	default:
+		return fmt.Errorf("unknown Cause value")
+	}
+
+	return nil
+}
+
+// Encode implementation
+func (r Cause) Encode(encoder scale.Encoder) (err error) {
+	if r.IsCanceledByUser {
+		err = encoder.PushByte(0)
+	} else if r.IsOutOfFunds {
+		err = encoder.PushByte(1)
+	}
+
+	return
+}
+
+// ServiceContractEvent is emitted when a service contract is created or changed
+type ServiceContractEvent struct {
+	Phase           types.Phase
+	ServiceContract ServiceContract
+	Topics          []types.Hash
+}
+
+// ServiceContractCanceled
+type ServiceContractCanceled struct {
+	Phase             types.Phase
+	ServiceContractID types.U64
+	Cause             Cause
+	Topics            []types.Hash
+}
+
+// ServiceContractBilled
+type ServiceContractBilled struct {
+	Phase           types.Phase
+	ServiceContract ServiceContract
+	Bill            ServiceContractBill
+	Amount          types.U128
+	Topics          []types.Hash
+}
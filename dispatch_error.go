@@ -0,0 +1,149 @@
+package substrate
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/centrifuge/go-substrate-rpc-client/v4/types"
+)
+
+// DispatchError is returned when an extrinsic fails on chain. For module
+// errors the pallet, error name and docs are resolved from the runtime metadata.
+//
+// Match a specific failure with errors.Is against one of the sentinel errors
+// below, or use errors.As to inspect the error.
+type DispatchError struct {
+	// Module is the index of the pallet that raised the error
+	Module uint8
+	// Index is the index of the error in the pallet errors
+	Index uint8
+	// Pallet is the name of the pallet that raised the error, for example 'SmartContractModule'.
+	// It's empty if the error is not a module error.
+	Pallet string
+	// Name is the error name, for example 'NodeHasActiveContracts'
+	Name string
+	// Docs of the error as defined in the pallet
+	Docs []string
+}
+
+// Error implements error
+func (e *DispatchError) Error() string {
+	if len(e.Pallet) == 0 {
+		return e.Name
+	}
+
+	return fmt.Sprintf("%s.%s", e.Pallet, e.Name)
+}
+
+// Is matches errors with the same pallet and name
+func (e *DispatchError) Is(target error) bool {
+	t, ok := target.(*DispatchError)
+	if !ok {
+		return false
+	}
+
+	return e.Pallet == t.Pallet && e.Name == t.Name
+}
+
+// Description returns the error docs as a single string
+func (e *DispatchError) Description() string {
+	return strings.TrimSpace(strings.Join(e.Docs, " "))
+}
+
+func moduleError(pallet, name string) *DispatchError {
+	return &DispatchError{Pallet: pallet, Name: name}
+}
+
+var (
+	ErrTwinNotExists                 = moduleError("SmartContractModule", "TwinNotExists")
+	ErrNodeNotExists                 = moduleError("SmartContractModule", "NodeNotExists")
+	ErrFarmNotExists                 = moduleError("SmartContractModule", "FarmNotExists")
+	ErrFarmHasNotEnoughPublicIPs     = moduleError("SmartContractModule", "FarmHasNotEnoughPublicIPs")
+	ErrFarmHasNotEnoughPublicIPsFree = moduleError("SmartContractModule", "FarmHasNotEnoughPublicIPsFree")
+	ErrContractNotExists             = moduleError("SmartContractModule", "ContractNotExists")
+	ErrContractIsNotUnique           = moduleError("SmartContractModule", "ContractIsNotUnique")
+	ErrNameExists                    = moduleError("SmartContractModule", "NameExists")
+	ErrNotEnoughResourcesOnNode      = moduleError("SmartContractModule", "NotEnoughResourcesOnNode")
+	ErrNodeHasActiveContracts        = moduleError("SmartContractModule", "NodeHasActiveContracts")
+	ErrNodeHasRentContract           = moduleError("SmartContractModule", "NodeHasRentContract")
+	ErrNodeNotAvailableToDeploy      = moduleError("SmartContractModule", "NodeNotAvailableToDeploy")
+
+	ErrTwinNotAuthorizedToUpdateContract = moduleError("SmartContractModule", "TwinNotAuthorizedToUpdateContract")
+	ErrTwinNotAuthorizedToCancelContract = moduleError("SmartContractModule", "TwinNotAuthorizedToCancelContract")
+
+	ErrBadOrigin = &DispatchError{Name: "BadOrigin"}
+)
+
+const dispatchErrorModule = 3
+
+// https://github.com/paritytech/substrate/blob/polkadot-v0.9.16/primitives/runtime/src/lib.rs#L470
+var dispatchErrors = []string{
+	"Other",
+	"CannotLookup",
+	"BadOrigin",
+	"Module",
+	"ConsumerRemaining",
+	"NoProviders",
+	"Token",
+	"Arithmetic",
+}
+
+// otherDispatchError creates the error for dispatch errors that are not raised by a pallet
+func otherDispatchError(variant uint8) *DispatchError {
+	if int(variant) >= len(dispatchErrors) {
+		return &DispatchError{Name: fmt.Sprintf("dispatch error %d", variant)}
+	}
+
+	return &DispatchError{Name: dispatchErrors[variant]}
+}
+
+// newDispatchError resolves the dispatch error using the runtime metadata.
+// For errors that are not raised by a pallet the event decoder stores the
+// variant of the error in de.Error.
+func newDispatchError(meta Meta, de types.DispatchError) *DispatchError {
+	if !de.HasModule {
+		return otherDispatchError(de.Error)
+	}
+
+	err := &DispatchError{
+		Module: de.Module,
+		Index:  de.Error,
+		Name:   fmt.Sprintf("error with code %d", de.Error),
+	}
+
+	if meta == nil || meta.Version != 14 {
+		return err
+	}
+
+	for _, pallet := range meta.AsMetadataV14.Pallets {
+		if uint8(pallet.Index) != de.Module {
+			continue
+		}
+
+		err.Pallet = string(pallet.Name)
+		if !pallet.HasErrors {
+			break
+		}
+
+		typ, ok := meta.AsMetadataV14.EfficientLookup[pallet.Errors.Type.Int64()]
+		if !ok {
+			break
+		}
+
+		for _, variant := range typ.Def.Variant.Variants {
+			if uint8(variant.Index) != de.Error {
+				continue
+			}
+
+			err.Name = string(variant.Name)
+			for _, doc := range variant.Docs {
+				err.Docs = append(err.Docs, string(doc))
+			}
+			break
+		}
+
+		break
+	}
+
+	return err
+}
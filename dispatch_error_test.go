@@ -0,0 +1,60 @@
+package substrate
+
+import (
+	"testing"
+
+	"github.com/centrifuge/go-substrate-rpc-client/v4/types"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDispatchError(t *testing.T) {
+	require := require.New(t)
+
+	meta := testMetadata(t, map[int64]*types.Si1Type{
+		1: variantType(
+			types.Si1Variant{Name: "TwinNotExists", Index: 0},
+			types.Si1Variant{Name: "NodeHasActiveContracts", Index: 21, Docs: []types.Text{"node has active contracts"}},
+		),
+	},
+		testPallet{name: "TfgridModule", index: 8},
+		testPallet{name: "SmartContractModule", index: 9, errors: 1},
+	)
+
+	err := newDispatchError(meta, types.DispatchError{HasModule: true, Module: 9, Error: 21})
+	require.Equal("SmartContractModule.NodeHasActiveContracts", err.Error())
+	require.Equal("node has active contracts", err.Description())
+
+	wrapped := errors.Wrap(err, "failed to delete node")
+	require.True(errors.Is(wrapped, ErrNodeHasActiveContracts))
+	require.False(errors.Is(wrapped, ErrNodeHasRentContract))
+
+	var de *DispatchError
+	require.True(errors.As(wrapped, &de))
+	require.EqualValues(9, de.Module)
+
+	// same error index raised by another pallet must not match
+	err = newDispatchError(meta, types.DispatchError{HasModule: true, Module: 8, Error: 21})
+	require.Equal("TfgridModule", err.Pallet)
+	require.False(errors.Is(err, ErrNodeHasActiveContracts))
+}
+
+func TestApplyExtrinsicResult(t *testing.T) {
+	require := require.New(t)
+
+	require.NoError(applyExtrinsicResult(nil, []byte{0, 0}))
+
+	err := applyExtrinsicResult(nil, []byte{0, 1, 2})
+	require.ErrorIs(err, ErrBadOrigin)
+
+	err = applyExtrinsicResult(nil, []byte{0, 1, 3, 9, 21})
+	var de *DispatchError
+	require.True(errors.As(err, &de))
+	require.EqualValues(9, de.Module)
+	require.EqualValues(21, de.Index)
+
+	// invalid transaction: payment
+	err = applyExtrinsicResult(nil, []byte{1, 0, 1})
+	require.ErrorIs(err, ErrInvalidTransaction)
+	require.Contains(err.Error(), "Payment")
+}
@@ -0,0 +1,234 @@
+package substrate
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/centrifuge/go-substrate-rpc-client/v4/types"
+	"github.com/pkg/errors"
+)
+
+// EventHandler handles a single event. The event is a value of the element
+// type of the matching EventRecords field, for example ContractCreated
+// for "SmartContractModule.ContractCreated".
+type EventHandler func(block BlockEvents, event interface{}) error
+
+// Dispatcher calls the registered handlers for the events of a block. Its
+// Handle method can be used as a Processor handler.
+//
+// Events are dispatched in phase order (initialization, then extrinsics in
+// order, then finalization). Events of the same phase are dispatched in the
+// order of the EventRecords fields since the decoded records don't keep
+// the order of events of different types.
+type Dispatcher struct {
+	handlers map[string][]EventHandler
+}
+
+// NewDispatcher creates a new dispatcher with no handlers
+func NewDispatcher() *Dispatcher {
+	return &Dispatcher{handlers: make(map[string][]EventHandler)}
+}
+
+// On registers a handler for the event with the given name in the form
+// "Pallet.Event", for example "TfgridModule.NodeStored". It panics if the
+// event is not known.
+func (d *Dispatcher) On(name string, handler EventHandler) {
+	field := strings.Replace(name, ".", "_", 1)
+	if _, ok := reflect.TypeOf(EventRecords{}).FieldByName(field); !ok || !strings.Contains(name, ".") {
+		panic(fmt.Sprintf("unknown event '%s'", name))
+	}
+
+	d.handlers[field] = append(d.handlers[field], handler)
+}
+
+// Handle dispatches all events of the block to the registered handlers, it
+// stops at the first handler error.
+func (d *Dispatcher) Handle(block BlockEvents) error {
+	if block.Events == nil || len(d.handlers) == 0 {
+		return nil
+	}
+
+	var events []dispatchEvent
+	d.collect(reflect.ValueOf(block.Events).Elem(), &events)
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].order < events[j].order
+	})
+
+	for _, event := range events {
+		for _, handler := range d.handlers[event.name] {
+			if err := handler(block, event.value); err != nil {
+				return errors.Wrapf(err, "failed to handle event '%s'", strings.Replace(event.name, "_", ".", 1))
+			}
+		}
+	}
+
+	return nil
+}
+
+type dispatchEvent struct {
+	name  string
+	order uint64
+	value interface{}
+}
+
+func (d *Dispatcher) collect(records reflect.Value, events *[]dispatchEvent) {
+	typ := records.Type()
+	for i := 0; i < records.NumField(); i++ {
+		field := records.Field(i)
+		if field.Kind() == reflect.Struct {
+			// embedded event records
+			d.collect(field, events)
+			continue
+		}
+
+		name := typ.Field(i).Name
+		if field.Kind() != reflect.Slice || len(d.handlers[name]) == 0 {
+			continue
+		}
+
+		for j := 0; j < field.Len(); j++ {
+			event := field.Index(j)
+			phase, _ := event.FieldByName("Phase").Interface().(types.Phase)
+			*events = append(*events, dispatchEvent{
+				name:  name,
+				order: phaseOrder(phase),
+				value: event.Interface(),
+			})
+		}
+	}
+}
+
+// phaseOrder sorts initialization first, then extrinsics, then finalization
+func phaseOrder(phase types.Phase) uint64 {
+	switch {
+	case phase.IsApplyExtrinsic:
+		return uint64(phase.AsApplyExtrinsic) + 1
+	case phase.IsFinalization:
+		return math.MaxUint64
+	default:
+		return 0
+	}
+}
+
+// OnContractCreated registers a handler for SmartContractModule.ContractCreated
+func (d *Dispatcher) OnContractCreated(fn func(ContractCreated) error) {
+	d.On("SmartContractModule.ContractCreated", func(_ BlockEvents, event interface{}) error {
+		return fn(event.(ContractCreated))
+	})
+}
+
+// OnContractUpdated registers a handler for SmartContractModule.ContractUpdated
+func (d *Dispatcher) OnContractUpdated(fn func(ContractUpdated) error) {
+	d.On("SmartContractModule.ContractUpdated", func(_ BlockEvents, event interface{}) error {
+		return fn(event.(ContractUpdated))
+	})
+}
+
+// OnNodeContractCanceled registers a handler for SmartContractModule.NodeContractCanceled
+func (d *Dispatcher) OnNodeContractCanceled(fn func(NodeContractCanceled) error) {
+	d.On("SmartContractModule.NodeContractCanceled", func(_ BlockEvents, event interface{}) error {
+		return fn(event.(NodeContractCanceled))
+	})
+}
+
+// OnNameContractCanceled registers a handler for SmartContractModule.NameContractCanceled
+func (d *Dispatcher) OnNameContractCanceled(fn func(NameContractCanceled) error) {
+	d.On("SmartContractModule.NameContractCanceled", func(_ BlockEvents, event interface{}) error {
+		return fn(event.(NameContractCanceled))
+	})
+}
+
+// OnRentContractCanceled registers a handler for SmartContractModule.RentContractCanceled
+func (d *Dispatcher) OnRentContractCanceled(fn func(RentContractCanceled) error) {
+	d.On("SmartContractModule.RentContractCanceled", func(_ BlockEvents, event interface{}) error {
+		return fn(event.(RentContractCanceled))
+	})
+}
+
+// OnContractBilled registers a handler for SmartContractModule.ContractBilled
+func (d *Dispatcher) OnContractBilled(fn func(ContractBilled) error) {
+	d.On("SmartContractModule.ContractBilled", func(_ BlockEvents, event interface{}) error {
+		return fn(event.(ContractBilled))
+	})
+}
+
+// OnNodeStored registers a handler for TfgridModule.NodeStored
+func (d *Dispatcher) OnNodeStored(fn func(NodeStored) error) {
+	d.On("TfgridModule.NodeStored", func(_ BlockEvents, event interface{}) error {
+		return fn(event.(NodeStored))
+	})
+}
+
+// OnNodeUpdated registers a handler for TfgridModule.NodeUpdated
+func (d *Dispatcher) OnNodeUpdated(fn func(NodeStored) error) {
+	d.On("TfgridModule.NodeUpdated", func(_ BlockEvents, event interface{}) error {
+		return fn(event.(NodeStored))
+	})
+}
+
+// OnNodeDeleted registers a handler for TfgridModule.NodeDeleted
+func (d *Dispatcher) OnNodeDeleted(fn func(NodeDeleted) error) {
+	d.On("TfgridModule.NodeDeleted", func(_ BlockEvents, event interface{}) error {
+		return fn(event.(NodeDeleted))
+	})
+}
+
+// OnNodeUptimeReported registers a handler for TfgridModule.NodeUptimeReported
+func (d *Dispatcher) OnNodeUptimeReported(fn func(NodeUptimeReported) error) {
+	d.On("TfgridModule.NodeUptimeReported", func(_ BlockEvents, event interface{}) error {
+		return fn(event.(NodeUptimeReported))
+	})
+}
+
+// OnPowerTargetChanged registers a handler for TfgridModule.PowerTargetChanged
+func (d *Dispatcher) OnPowerTargetChanged(fn func(PowerTargetChanged) error) {
+	d.On("TfgridModule.PowerTargetChanged", func(_ BlockEvents, event interface{}) error {
+		return fn(event.(PowerTargetChanged))
+	})
+}
+
+// OnPowerStateChanged registers a handler for TfgridModule.PowerStateChanged
+func (d *Dispatcher) OnPowerStateChanged(fn func(PowerStateChanged) error) {
+	d.On("TfgridModule.PowerStateChanged", func(_ BlockEvents, event interface{}) error {
+		return fn(event.(PowerStateChanged))
+	})
+}
+
+// OnTwinStored registers a handler for TfgridModule.TwinStored
+func (d *Dispatcher) OnTwinStored(fn func(TwinStored) error) {
+	d.On("TfgridModule.TwinStored", func(_ BlockEvents, event interface{}) error {
+		return fn(event.(TwinStored))
+	})
+}
+
+// OnTwinDeleted registers a handler for TfgridModule.TwinDeleted
+func (d *Dispatcher) OnTwinDeleted(fn func(TwinDeleted) error) {
+	d.On("TfgridModule.TwinDeleted", func(_ BlockEvents, event interface{}) error {
+		return fn(event.(TwinDeleted))
+	})
+}
+
+// OnFarmStored registers a handler for TfgridModule.FarmStored
+func (d *Dispatcher) OnFarmStored(fn func(FarmStored) error) {
+	d.On("TfgridModule.FarmStored", func(_ BlockEvents, event interface{}) error {
+		return fn(event.(FarmStored))
+	})
+}
+
+// OnFarmDeleted registers a handler for TfgridModule.FarmDeleted
+func (d *Dispatcher) OnFarmDeleted(fn func(FarmDeleted) error) {
+	d.On("TfgridModule.FarmDeleted", func(_ BlockEvents, event interface{}) error {
+		return fn(event.(FarmDeleted))
+	})
+}
+
+// OnExtrinsicFailed registers a handler for System.ExtrinsicFailed
+func (d *Dispatcher) OnExtrinsicFailed(fn func(types.EventSystemExtrinsicFailed) error) {
+	d.On("System.ExtrinsicFailed", func(_ BlockEvents, event interface{}) error {
+		return fn(event.(types.EventSystemExtrinsicFailed))
+	})
+}
@@ -0,0 +1,46 @@
+package substrate
+
+import (
+	"testing"
+
+	"github.com/centrifuge/go-substrate-rpc-client/v4/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDispatcher(t *testing.T) {
+	require := require.New(t)
+
+	apply := func(index uint32) types.Phase {
+		return types.Phase{IsApplyExtrinsic: true, AsApplyExtrinsic: index}
+	}
+
+	events := &EventRecords{}
+	events.SmartContractModule_ContractCreated = []ContractCreated{
+		{Phase: apply(2), Contract: Contract{ContractID: 2}},
+		{Phase: apply(0), Contract: Contract{ContractID: 1}},
+	}
+	events.TfgridModule_NodeStored = []NodeStored{
+		{Phase: apply(1), Node: Node{ID: 10}},
+	}
+	events.SmartContractModule_ContractBilled = []ContractBilled{
+		{Phase: types.Phase{IsFinalization: true}},
+	}
+
+	var order []string
+	d := NewDispatcher()
+	d.OnContractCreated(func(e ContractCreated) error {
+		order = append(order, "contract")
+		return nil
+	})
+	d.OnNodeStored(func(e NodeStored) error {
+		order = append(order, "node")
+		return nil
+	})
+
+	require.NoError(d.Handle(BlockEvents{Number: 1, Events: events}))
+	require.Equal([]string{"contract", "node", "contract"}, order)
+
+	require.Panics(func() {
+		d.On("TfgridModule.Unknown", nil)
+	})
+}
@@ -0,0 +1,625 @@
+package substrate
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"reflect"
+
+	"github.com/centrifuge/go-substrate-rpc-client/v4/scale"
+	"github.com/centrifuge/go-substrate-rpc-client/v4/types"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	// ErrUnknownEvent is the reason of skipped events that have
+	// no matching field in EventRecords
+	ErrUnknownEvent = fmt.Errorf("unknown event")
+)
+
+// legacyEvent is the layout an event had in older runtime versions
+type legacyEvent struct {
+	// until is the first spec version with the current layout
+	until uint32
+	// field is the EventRecords field of the old layout
+	field string
+}
+
+// legacyEvents are the events whose fields changed in place, events of
+// runtime versions before until are decoded into the legacy field
+var legacyEvents = map[string]legacyEvent{
+	"TfgridModule_NodePublicConfigStored": {until: optionalPublicConfigSpec, field: "TfgridModule_NodePublicConfigStoredV1"},
+}
+
+// runtimeVersion is the start of the encoded System.Version constant
+type runtimeVersion struct {
+	SpecName         string
+	ImplName         string
+	AuthoringVersion types.U32
+	SpecVersion      types.U32
+}
+
+// specVersion returns the spec version of the runtime the metadata
+// belongs to, or 0 if the metadata has no System.Version constant
+func specVersion(meta *types.MetadataV14) uint32 {
+	for _, pallet := range meta.Pallets {
+		if pallet.Name != "System" {
+			continue
+		}
+
+		for _, constant := range pallet.Constants {
+			if constant.Name != "Version" {
+				continue
+			}
+
+			var version runtimeVersion
+			if err := types.DecodeFromBytes(constant.Value, &version); err != nil {
+				log.Debug().Err(err).Msg("failed to decode runtime version constant")
+				return 0
+			}
+
+			return uint32(version.SpecVersion)
+		}
+	}
+
+	return 0
+}
+
+// RawEvent is an event that was skipped while decoding the block events,
+// either because EventRecords has no field for it, or because the
+// field type doesn't match the event as defined in the metadata.
+type RawEvent struct {
+	Phase  types.Phase
+	Pallet string
+	Event  string
+	// Bytes are the scale encoded event fields
+	Bytes  []byte
+	Topics []types.Hash
+	// Reason wraps ErrUnknownEvent or ErrFailedToDecode
+	Reason error
+}
+
+// decodeEventRecords decodes the events into records, events that can't be decoded
+// are skipped and added to records.Skipped. The types information of the V14
+// metadata is used to find where each event ends, so a bad event never
+// affects the rest of the block.
+func decodeEventRecords(meta Meta, raw []byte, records *EventRecords) error {
+	if meta.Version != 14 {
+		return types.EventRecordsRaw(raw).DecodeEventRecords(meta, records)
+	}
+
+	reader := &typeReader{meta: &meta.AsMetadataV14, data: raw, spec: specVersion(&meta.AsMetadataV14)}
+	count, err := reader.compact()
+	if err != nil {
+		return errors.Wrap(err, "failed to read events count")
+	}
+
+	target := reflect.ValueOf(records).Elem()
+	for i := uint64(0); i < count; i++ {
+		if _, err := reader.event(target, records); err != nil {
+			return errors.Wrapf(ErrFailedToDecode, "event %d: %s", i, err)
+		}
+	}
+
+	return nil
+}
+
+// eventRecord is a single event of a block, unlike EventRecords
+// a list of eventRecord keeps the order of the events
+type eventRecord struct {
+	Phase types.Phase
+	// Event holds only this event
+	Event EventRecords
+}
+
+// decodeEventList decodes the events like decodeEventRecords, but keeps each
+// event in its own record so the order of the events is known. The order is
+// only kept with V14 metadata, older events are listed by type.
+func decodeEventList(meta Meta, raw []byte) ([]eventRecord, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+
+	if meta.Version != 14 {
+		var records EventRecords
+		if err := decodeEventRecords(meta, raw, &records); err != nil {
+			return nil, err
+		}
+
+		return splitEvents(&records), nil
+	}
+
+	reader := &typeReader{meta: &meta.AsMetadataV14, data: raw, spec: specVersion(&meta.AsMetadataV14)}
+	count, err := reader.compact()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read events count")
+	}
+
+	if err := reader.fits(count); err != nil {
+		return nil, errors.Wrap(err, "failed to read events")
+	}
+
+	list := make([]eventRecord, 0, count)
+	for i := uint64(0); i < count; i++ {
+		var record eventRecord
+		record.Phase, err = reader.event(reflect.ValueOf(&record.Event).Elem(), &record.Event)
+		if err != nil {
+			return nil, errors.Wrapf(ErrFailedToDecode, "event %d: %s", i, err)
+		}
+
+		list = append(list, record)
+	}
+
+	return list, nil
+}
+
+// splitEvents lists every event of records in its own record
+func splitEvents(records *EventRecords) (list []eventRecord) {
+	var walk func(src reflect.Value, path []int)
+	walk = func(src reflect.Value, path []int) {
+		for i := 0; i < src.NumField(); i++ {
+			field := src.Field(i)
+			switch field.Kind() {
+			case reflect.Struct:
+				// embedded event records
+				walk(field, append(path, i))
+			case reflect.Slice:
+				for j := 0; j < field.Len(); j++ {
+					event := field.Index(j)
+					phase, _ := event.FieldByName("Phase").Interface().(types.Phase)
+
+					record := eventRecord{Phase: phase}
+					dst := reflect.ValueOf(&record.Event).Elem().FieldByIndex(append(path, i))
+					dst.Set(reflect.Append(dst, event))
+					list = append(list, record)
+				}
+			}
+		}
+	}
+
+	walk(reflect.ValueOf(records).Elem(), nil)
+	return list
+}
+
+// typeReader walks scale encoded data using the types in the metadata
+type typeReader struct {
+	meta *types.MetadataV14
+	data []byte
+	pos  int
+	// spec is the spec version of the runtime, 0 if unknown
+	spec uint32
+	// dispatchErrors are the [start, end) positions of the dispatch
+	// errors read since the last reset
+	dispatchErrors [][2]int
+}
+
+func (r *typeReader) take(n int) ([]byte, error) {
+	if n < 0 || r.pos+n > len(r.data) {
+		return nil, fmt.Errorf("unexpected end of data")
+	}
+
+	data := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return data, nil
+}
+
+// fits checks that count values of at least one byte each can be read
+func (r *typeReader) fits(count uint64) error {
+	if count > uint64(len(r.data)-r.pos) {
+		return fmt.Errorf("%d values don't fit in the %d bytes left", count, len(r.data)-r.pos)
+	}
+
+	return nil
+}
+
+func (r *typeReader) readByte() (byte, error) {
+	data, err := r.take(1)
+	if err != nil {
+		return 0, err
+	}
+
+	return data[0], nil
+}
+
+// compact reads a compact encoded integer
+func (r *typeReader) compact() (uint64, error) {
+	value, err := r.compactBig()
+	if err != nil {
+		return 0, err
+	}
+
+	if !value.IsUint64() {
+		return 0, fmt.Errorf("compact value too big")
+	}
+
+	return value.Uint64(), nil
+}
+
+// compactBig reads a compact encoded integer of any size
+func (r *typeReader) compactBig() (*big.Int, error) {
+	b, err := r.readByte()
+	if err != nil {
+		return nil, err
+	}
+
+	var data []byte
+	switch b & 0b11 {
+	case 0:
+		return big.NewInt(int64(b >> 2)), nil
+	case 1:
+		data, err = r.take(1)
+		if err != nil {
+			return nil, err
+		}
+		return big.NewInt(int64(binary.LittleEndian.Uint16([]byte{b, data[0]}) >> 2)), nil
+	case 2:
+		data, err = r.take(3)
+		if err != nil {
+			return nil, err
+		}
+		return big.NewInt(int64(binary.LittleEndian.Uint32([]byte{b, data[0], data[1], data[2]}) >> 2)), nil
+	default:
+		data, err = r.take(int(b>>2) + 4)
+		if err != nil {
+			return nil, err
+		}
+
+		return littleEndian(data), nil
+	}
+}
+
+// littleEndian converts a little endian unsigned integer to a big.Int
+func littleEndian(data []byte) *big.Int {
+	be := make([]byte, len(data))
+	for i := range data {
+		be[len(data)-1-i] = data[i]
+	}
+
+	return big.NewInt(0).SetBytes(be)
+}
+
+// event reads a single event record and adds it to the records,
+// it returns the phase of the event
+func (r *typeReader) event(target reflect.Value, records *EventRecords) (phase types.Phase, err error) {
+	start := r.pos
+	kind, err := r.readByte()
+	if err != nil {
+		return phase, err
+	}
+
+	switch kind {
+	case 0:
+		index, err := r.take(4)
+		if err != nil {
+			return phase, err
+		}
+		phase = types.Phase{IsApplyExtrinsic: true, AsApplyExtrinsic: binary.LittleEndian.Uint32(index)}
+	case 1:
+		phase.IsFinalization = true
+	case 2:
+		phase.IsInitialization = true
+	default:
+		return phase, fmt.Errorf("invalid phase %d", kind)
+	}
+
+	phaseBytes := r.data[start:r.pos]
+
+	id, err := r.take(2)
+	if err != nil {
+		return phase, err
+	}
+
+	pallet, variant, err := r.variant(id[0], id[1], palletEvents)
+	if err != nil {
+		return phase, errors.Wrap(err, "event")
+	}
+
+	fieldsStart := r.pos
+	r.dispatchErrors = nil
+	for _, field := range variant.Fields {
+		if err := r.skip(field.Type.Int64()); err != nil {
+			return phase, errors.Wrapf(err, "failed to read %s.%s", pallet.Name, variant.Name)
+		}
+	}
+	fields := r.data[fieldsStart:r.pos]
+	decodable := r.dispatchable(fieldsStart, r.pos)
+
+	topicsStart := r.pos
+	topics, err := r.compact()
+	if err != nil {
+		return phase, err
+	}
+
+	if err := r.fits(topics); err != nil {
+		return phase, err
+	}
+
+	if _, err := r.take(int(topics) * 32); err != nil {
+		return phase, err
+	}
+	topicsBytes := r.data[topicsStart:r.pos]
+
+	name := fmt.Sprintf("%s_%s", pallet.Name, variant.Name)
+	if legacy, ok := legacyEvents[name]; ok && r.spec != 0 && r.spec < legacy.until {
+		name = legacy.field
+	}
+
+	field := target.FieldByName(name)
+	if !field.IsValid() || field.Kind() != reflect.Slice {
+		return phase, r.skipped(records, phase, string(pallet.Name), string(variant.Name), fields, topicsBytes, ErrUnknownEvent)
+	}
+
+	// the event types are structs of Phase, the event fields then Topics
+	var buf bytes.Buffer
+	buf.Write(phaseBytes)
+	buf.Write(decodable)
+	buf.Write(topicsBytes)
+
+	reader := bytes.NewReader(buf.Bytes())
+	event := reflect.New(field.Type().Elem())
+	if err := scale.NewDecoder(reader).Decode(event.Interface()); err != nil {
+		return phase, r.skipped(records, phase, string(pallet.Name), string(variant.Name), fields, topicsBytes, errors.Wrap(ErrFailedToDecode, err.Error()))
+	}
+
+	if reader.Len() != 0 {
+		return phase, r.skipped(records, phase, string(pallet.Name), string(variant.Name), fields, topicsBytes, errors.Wrapf(ErrFailedToDecode, "%d bytes left", reader.Len()))
+	}
+
+	field.Set(reflect.Append(field, event.Elem()))
+	return phase, nil
+}
+
+// dispatchable returns the data between start and end with the dispatch
+// errors rewritten in the layout types.DispatchError decodes. It only knows
+// module errors and reads a byte after any other variant, so other variants
+// are written with the variant index in that byte, which newDispatchError
+// uses to resolve the error name. Module errors keep the pallet index and
+// the first byte of the error, newer runtimes encode the error as [u8; 4].
+func (r *typeReader) dispatchable(start, end int) []byte {
+	if len(r.dispatchErrors) == 0 {
+		return r.data[start:end]
+	}
+
+	var buf bytes.Buffer
+	pos := start
+	for _, span := range r.dispatchErrors {
+		buf.Write(r.data[pos:span[0]])
+
+		variant := r.data[span[0]]
+		if variant == dispatchErrorModule && span[1]-span[0] >= 3 {
+			buf.Write(r.data[span[0] : span[0]+3])
+		} else {
+			buf.Write([]byte{variant, variant})
+		}
+
+		pos = span[1]
+	}
+	buf.Write(r.data[pos:end])
+
+	return buf.Bytes()
+}
+
+// isDispatchError checks if the type is sp_runtime::DispatchError
+func isDispatchError(typ *types.Si1Type) bool {
+	return len(typ.Path) > 0 && typ.Path[len(typ.Path)-1] == "DispatchError"
+}
+
+func (r *typeReader) skipped(records *EventRecords, phase types.Phase, pallet, event string, fields, topics []byte, reason error) error {
+	raw := RawEvent{
+		Phase:  phase,
+		Pallet: pallet,
+		Event:  event,
+		Bytes:  append([]byte(nil), fields...),
+		Reason: reason,
+	}
+
+	if err := types.DecodeFromBytes(topics, &raw.Topics); err != nil {
+		return err
+	}
+
+	log.Debug().Err(reason).Str("event", fmt.Sprintf("%s.%s", pallet, event)).Msg("skipping event")
+	records.Skipped = append(records.Skipped, raw)
+	return nil
+}
+
+// variant finds the event or call (depending on kind) with the given
+// pallet and variant index
+func (r *typeReader) variant(palletIndex, index byte, kind variantKind) (*types.PalletMetadataV14, *types.Si1Variant, error) {
+	for i := range r.meta.Pallets {
+		pallet := &r.meta.Pallets[i]
+		if uint8(pallet.Index) != palletIndex {
+			continue
+		}
+
+		has, id := kind(pallet)
+		if !has {
+			break
+		}
+
+		typ, ok := r.meta.EfficientLookup[id.Int64()]
+		if !ok {
+			break
+		}
+
+		for j := range typ.Def.Variant.Variants {
+			variant := &typ.Def.Variant.Variants[j]
+			if uint8(variant.Index) == index {
+				return pallet, variant, nil
+			}
+		}
+	}
+
+	return nil, nil, fmt.Errorf("[%d, %d] not found in metadata", palletIndex, index)
+}
+
+// variantKind selects the events or calls type of a pallet
+type variantKind func(pallet *types.PalletMetadataV14) (bool, types.Si1LookupTypeID)
+
+func palletEvents(pallet *types.PalletMetadataV14) (bool, types.Si1LookupTypeID) {
+	return pallet.HasEvents, pallet.Events.Type
+}
+
+func palletCalls(pallet *types.PalletMetadataV14) (bool, types.Si1LookupTypeID) {
+	return pallet.HasCalls, pallet.Calls.Type
+}
+
+// skip moves over a value of the given type
+func (r *typeReader) skip(id int64) error {
+	typ, ok := r.meta.EfficientLookup[id]
+	if !ok {
+		return fmt.Errorf("type %d not found in metadata", id)
+	}
+
+	def := &typ.Def
+	switch {
+	case def.IsComposite:
+		for _, field := range def.Composite.Fields {
+			if err := r.skip(field.Type.Int64()); err != nil {
+				return err
+			}
+		}
+	case def.IsVariant:
+		start := r.pos
+		index, err := r.readByte()
+		if err != nil {
+			return err
+		}
+
+		if isDispatchError(typ) {
+			defer func() {
+				r.dispatchErrors = append(r.dispatchErrors, [2]int{start, r.pos})
+			}()
+		}
+
+		for _, variant := range def.Variant.Variants {
+			if uint8(variant.Index) != index {
+				continue
+			}
+
+			for _, field := range variant.Fields {
+				if err := r.skip(field.Type.Int64()); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		}
+
+		return fmt.Errorf("variant %d of type %d not found", index, id)
+	case def.IsSequence:
+		count, err := r.compact()
+		if err != nil {
+			return err
+		}
+
+		return r.skipN(def.Sequence.Type.Int64(), count)
+	case def.IsArray:
+		return r.skipN(def.Array.Type.Int64(), uint64(def.Array.Len))
+	case def.IsTuple:
+		for _, elem := range def.Tuple {
+			if err := r.skip(elem.Int64()); err != nil {
+				return err
+			}
+		}
+	case def.IsPrimitive:
+		return r.primitive(def.Primitive.Si0TypeDefPrimitive)
+	case def.IsCompact:
+		_, err := r.compact()
+		return err
+	case def.IsBitSequence:
+		bits, err := r.compact()
+		if err != nil {
+			return err
+		}
+
+		size, err := r.primitiveSize(def.BitSequence.BitStoreType.Int64())
+		if err != nil {
+			return err
+		}
+
+		store := uint64(size * 8)
+		_, err = r.take(int((bits + store - 1) / store * uint64(size)))
+		return err
+	default:
+		return fmt.Errorf("unsupported type %d", id)
+	}
+
+	return nil
+}
+
+// skipN skips count values of the given type
+func (r *typeReader) skipN(id int64, count uint64) error {
+	if err := r.fits(count); err != nil {
+		return err
+	}
+
+	if size, err := r.primitiveSize(id); err == nil {
+		// fast path for byte arrays and alike
+		_, err := r.take(int(count) * size)
+		return err
+	}
+
+	for i := uint64(0); i < count; i++ {
+		if err := r.skip(id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// primitiveSize returns the size of fixed size primitive types
+func (r *typeReader) primitiveSize(id int64) (int, error) {
+	typ, ok := r.meta.EfficientLookup[id]
+	if !ok || !typ.Def.IsPrimitive {
+		return 0, fmt.Errorf("type %d is not a primitive", id)
+	}
+
+	size, ok := fixedSize(typ.Def.Primitive.Si0TypeDefPrimitive)
+	if !ok {
+		return 0, fmt.Errorf("type %d is not fixed size", id)
+	}
+
+	return size, nil
+}
+
+func (r *typeReader) primitive(primitive types.Si0TypeDefPrimitive) error {
+	if primitive == types.IsStr {
+		size, err := r.compact()
+		if err != nil {
+			return err
+		}
+
+		_, err = r.take(int(size))
+		return err
+	}
+
+	size, ok := fixedSize(primitive)
+	if !ok {
+		return fmt.Errorf("unknown primitive %d", primitive)
+	}
+
+	_, err := r.take(size)
+	return err
+}
+
+func fixedSize(primitive types.Si0TypeDefPrimitive) (int, bool) {
+	switch primitive {
+	case types.IsBool, types.IsU8, types.IsI8:
+		return 1, true
+	case types.IsU16, types.IsI16:
+		return 2, true
+	case types.IsChar, types.IsU32, types.IsI32:
+		return 4, true
+	case types.IsU64, types.IsI64:
+		return 8, true
+	case types.IsU128, types.IsI128:
+		return 16, true
+	case types.IsU256, types.IsI256:
+		return 32, true
+	}
+
+	return 0, false
+}
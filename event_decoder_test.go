@@ -0,0 +1,152 @@
+package substrate
+
+import (
+	"testing"
+
+	"github.com/centrifuge/go-substrate-rpc-client/v4/types"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeEventRecords(t *testing.T) {
+	require := require.New(t)
+
+	meta := testMetadata(t, map[int64]*types.Si1Type{
+		1: variantType(
+			types.Si1Variant{Name: "NodeDeleted", Index: 0, Fields: typeFields(2)},
+			types.Si1Variant{Name: "SomethingNew", Index: 1, Fields: typeFields(3)},
+			// changed in the runtime from u32 to u64
+			types.Si1Variant{Name: "TwinDeleted", Index: 2, Fields: typeFields(3)},
+		),
+		2: primitiveType(types.IsU32),
+		3: primitiveType(types.IsU64),
+	}, testPallet{name: "TfgridModule", index: 11, events: 1})
+
+	raw := []byte{4 << 2}
+	// phase, pallet and event index, fields, topics
+	raw = append(raw, 0, 1, 0, 0, 0, 11, 0, 5, 0, 0, 0, 0)
+	raw = append(raw, 0, 1, 0, 0, 0, 11, 1, 7, 0, 0, 0, 0, 0, 0, 0, 0)
+	raw = append(raw, 0, 2, 0, 0, 0, 11, 2, 9, 0, 0, 0, 0, 0, 0, 0, 0)
+	raw = append(raw, 1, 11, 0, 6, 0, 0, 0, 0)
+
+	var records EventRecords
+	require.NoError(decodeEventRecords(meta, raw, &records))
+
+	require.Len(records.TfgridModule_NodeDeleted, 2)
+	require.EqualValues(5, records.TfgridModule_NodeDeleted[0].Node)
+	require.EqualValues(1, records.TfgridModule_NodeDeleted[0].Phase.AsApplyExtrinsic)
+	require.EqualValues(6, records.TfgridModule_NodeDeleted[1].Node)
+	require.True(records.TfgridModule_NodeDeleted[1].Phase.IsFinalization)
+	require.Empty(records.TfgridModule_TwinDeleted)
+
+	require.Len(records.Skipped, 2)
+	require.Equal("SomethingNew", records.Skipped[0].Event)
+	require.True(errors.Is(records.Skipped[0].Reason, ErrUnknownEvent))
+	require.Equal([]byte{7, 0, 0, 0, 0, 0, 0, 0}, records.Skipped[0].Bytes)
+	require.Equal("TwinDeleted", records.Skipped[1].Event)
+	require.True(errors.Is(records.Skipped[1].Reason, ErrFailedToDecode))
+
+	// an event missing from the metadata can't be skipped
+	err := decodeEventRecords(meta, []byte{1 << 2, 1, 11, 9, 0}, &records)
+	require.ErrorIs(err, ErrFailedToDecode)
+}
+
+func TestDecodeDispatchErrors(t *testing.T) {
+	require := require.New(t)
+
+	dispatchError := variantType(
+		types.Si1Variant{Name: "Other", Index: 0},
+		types.Si1Variant{Name: "CannotLookup", Index: 1},
+		types.Si1Variant{Name: "BadOrigin", Index: 2},
+		types.Si1Variant{Name: "Module", Index: 3, Fields: typeFields(3)},
+	)
+	dispatchError.Path = types.Si1Path{"sp_runtime", "DispatchError"}
+
+	meta := testMetadata(t, map[int64]*types.Si1Type{
+		1: variantType(types.Si1Variant{Name: "ExtrinsicFailed", Index: 1, Fields: typeFields(2, 5)}),
+		2: dispatchError,
+		// newer runtimes encode the module error as [u8; 4]
+		3: compositeType(4, 6),
+		4: primitiveType(types.IsU8),
+		5: compositeType(7, 4, 4),
+		6: {Def: types.Si1TypeDef{IsArray: true, Array: types.Si1TypeDefArray{Len: 4, Type: typeID(4)}}},
+		7: primitiveType(types.IsU64),
+	}, testPallet{name: "System", index: 0, events: 1})
+
+	info := []byte{1, 0, 0, 0, 0, 0, 0, 0, 0, 1}
+
+	raw := []byte{2 << 2}
+	// phase, pallet and event index, dispatch error, info, topics
+	raw = append(raw, 0, 1, 0, 0, 0, 0, 1, 2)
+	raw = append(raw, info...)
+	raw = append(raw, 0)
+	raw = append(raw, 0, 2, 0, 0, 0, 0, 1, 3, 9, 21, 0, 0, 0)
+	raw = append(raw, info...)
+	raw = append(raw, 0)
+
+	var records EventRecords
+	require.NoError(decodeEventRecords(meta, raw, &records))
+	require.Empty(records.Skipped)
+	require.Len(records.System_ExtrinsicFailed, 2)
+
+	failed := records.System_ExtrinsicFailed[0]
+	require.ErrorIs(newDispatchError(meta, failed.DispatchError), ErrBadOrigin)
+	require.EqualValues(1, failed.DispatchInfo.Weight)
+	require.True(failed.DispatchInfo.PaysFee.IsNo)
+
+	failed = records.System_ExtrinsicFailed[1]
+	err := newDispatchError(meta, failed.DispatchError)
+	require.EqualValues(9, err.Module)
+	require.EqualValues(21, err.Index)
+	require.EqualValues(1, failed.DispatchInfo.Weight)
+}
+
+func TestDecodeLegacyEvents(t *testing.T) {
+	require := require.New(t)
+
+	// metadata of a runtime with given spec version, config is the
+	// type of the public config in NodePublicConfigStored
+	metadata := func(spec uint32, config int64) *types.Metadata {
+		return testMetadata(t, map[int64]*types.Si1Type{
+			1: variantType(types.Si1Variant{Name: "NodePublicConfigStored", Index: 0, Fields: typeFields(2, config)}),
+			2: primitiveType(types.IsU32),
+			3: compositeType(4, 4, 4, 4, 4),
+			4: primitiveType(types.IsStr),
+			5: optionType(3),
+		},
+			testPallet{name: "System", index: 0, spec: spec},
+			testPallet{name: "TfgridModule", index: 11, events: 1},
+		)
+	}
+
+	// events encodes a single NodePublicConfigStored event of node 1
+	events := func(config interface{}) []byte {
+		fields, err := types.EncodeToBytes(config)
+		require.NoError(err)
+
+		raw := []byte{1 << 2, 0, 1, 0, 0, 0, 11, 0, 1, 0, 0, 0}
+		raw = append(raw, fields...)
+		return append(raw, 0)
+	}
+
+	config := PublicConfig{IPv4: "185.0.0.2/24", GWv4: "185.0.0.1"}
+
+	var records EventRecords
+	require.NoError(decodeEventRecords(metadata(optionalPublicConfigSpec-1, 3), events(config), &records))
+	require.Empty(records.Skipped)
+	require.Empty(records.TfgridModule_NodePublicConfigStored)
+	require.Len(records.TfgridModule_NodePublicConfigStoredV1, 1)
+	require.EqualValues(1, records.TfgridModule_NodePublicConfigStoredV1[0].Node)
+	require.Equal(config, records.TfgridModule_NodePublicConfigStoredV1[0].Config)
+
+	optional := OptionPublicConfig{HasValue: true, AsValue: config}
+	// without the version constant the current layout is used
+	for _, spec := range []uint32{optionalPublicConfigSpec, 0} {
+		records = EventRecords{}
+		require.NoError(decodeEventRecords(metadata(spec, 5), events(optional), &records))
+		require.Empty(records.Skipped)
+		require.Empty(records.TfgridModule_NodePublicConfigStoredV1)
+		require.Len(records.TfgridModule_NodePublicConfigStored, 1)
+		require.Equal(optional, records.TfgridModule_NodePublicConfigStored[0].Config)
+	}
+}
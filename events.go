@@ -4,9 +4,26 @@ import (
 	"github.com/centrifuge/go-substrate-rpc-client/v4/types"
 )
 
-// TODO: add all events from SmartContractModule and TfgridModule
+// Events of SmartContractModule and TfgridModule follow the tfchain runtime.
+// Events that were removed from the runtime are kept so blocks of older
+// runtime versions can still be decoded.
 
+// NodePublicConfig is emitted when the public config of a node is set
+// or removed
 type NodePublicConfig struct {
+	Phase  types.Phase
+	Node   types.U32
+	Config OptionPublicConfig
+	Topics []types.Hash
+}
+
+// optionalPublicConfigSpec is the first tfchain spec version where the
+// public config of NodePublicConfigStored is optional
+const optionalPublicConfigSpec = 118
+
+// NodePublicConfigV1 is NodePublicConfig as emitted by runtimes before
+// optionalPublicConfigSpec, when the config could not be removed
+type NodePublicConfigV1 struct {
 	Phase  types.Phase
 	Node   types.U32
 	Config PublicConfig
@@ -168,9 +185,10 @@ type ConnectionPriceSet struct {
 }
 
 type NodeCertificationSet struct {
-	Phase  types.Phase
-	Price  types.U32
-	Topics []types.Hash
+	Phase         types.Phase
+	NodeID        types.U32
+	Certification NodeCertification
+	Topics        []types.Hash
 }
 
 type NodeCertifierAdded struct {
@@ -212,6 +230,39 @@ type FarmCertificationSet struct {
 	Topics        []types.Hash
 }
 
+// TwinAccountBounded is emitted when an account is bound to a twin
+type TwinAccountBounded struct {
+	Phase   types.Phase
+	Twin    types.U32
+	Account AccountID
+	Topics  []types.Hash
+}
+
+// ZosVersionUpdated is emitted when the zos version is set
+type ZosVersionUpdated struct {
+	Phase   types.Phase
+	Version string
+	Topics  []types.Hash
+}
+
+// PowerTargetChanged is emitted when the farmer changes the power target of a node
+type PowerTargetChanged struct {
+	Phase  types.Phase
+	Farm   types.U32
+	Node   types.U32
+	Target Power
+	Topics []types.Hash
+}
+
+// PowerStateChanged is emitted when a node reports a new power state
+type PowerStateChanged struct {
+	Phase  types.Phase
+	Farm   types.U32
+	Node   types.U32
+	State  PowerState
+	Topics []types.Hash
+}
+
 type PriceStored struct {
 	Phase types.Phase
 	// in rust this is a U16F16 which is a custom type of 4 bytes width to
@@ -270,6 +321,36 @@ type MemberAdded struct {
 	Topics []types.Hash
 }
 
+// UtilityItemCompleted is emitted after each call in a batch is executed
+type UtilityItemCompleted struct {
+	Phase  types.Phase
+	Topics []types.Hash
+}
+
+// UtilityItemFailed is emitted after a call in a force_batch failed
+type UtilityItemFailed struct {
+	Phase         types.Phase
+	DispatchError types.DispatchError
+	Topics        []types.Hash
+}
+
+// BalancesWithdraw is emitted when the transaction fee is withdrawn from an account
+type BalancesWithdraw struct {
+	Phase  types.Phase
+	Who    types.AccountID
+	Amount types.U128
+	Topics []types.Hash
+}
+
+// TransactionFeePaid is emitted when the transaction fee is paid by an account
+type TransactionFeePaid struct {
+	Phase     types.Phase
+	Who       types.AccountID
+	ActualFee types.U128
+	Tip       types.U128
+	Topics    []types.Hash
+}
+
 // EventRecords is a struct that extends the default events with our events
 type EventRecords struct {
 	types.EventRecords
@@ -289,6 +370,18 @@ type EventRecords struct {
 	SmartContractModule_ContractGracePeriodStarted   []ContractGracePeriodStarted   //nolint:stylecheck,golint
 	SmartContractModule_ContractGracePeriodEnded     []ContractGracePeriodEnded     //nolint:stylecheck,golint
 	SmartContractModule_NodeMarkedAsDedicated        []NodeMarkAsDedicated          //nolint:stylecheck,golint
+	SmartContractModule_SolutionProviderCreated      []SolutionProviderCreated      //nolint:stylecheck,golint
+	SmartContractModule_SolutionProviderApproved     []SolutionProviderApproved     //nolint:stylecheck,golint
+	SmartContractModule_BillingFrequencyChanged      []BillingFrequencyChanged      //nolint:stylecheck,golint
+	SmartContractModule_NodeExtraFeeSet              []NodeExtraFeeSet              //nolint:stylecheck,golint
+
+	// service contract events
+	SmartContractModule_ServiceContractCreated     []ServiceContractEvent    //nolint:stylecheck,golint
+	SmartContractModule_ServiceContractMetadataSet []ServiceContractEvent    //nolint:stylecheck,golint
+	SmartContractModule_ServiceContractFeesSet     []ServiceContractEvent    //nolint:stylecheck,golint
+	SmartContractModule_ServiceContractApproved    []ServiceContractEvent    //nolint:stylecheck,golint
+	SmartContractModule_ServiceContractCanceled    []ServiceContractCanceled //nolint:stylecheck,golint
+	SmartContractModule_ServiceContractBilled      []ServiceContractBilled   //nolint:stylecheck,golint
 
 	// farm events
 	TfgridModule_FarmStored  []FarmStored  //nolint:stylecheck,golint
@@ -301,6 +394,10 @@ type EventRecords struct {
 	TfgridModule_NodeDeleted            []NodeDeleted        //nolint:stylecheck,golint
 	TfgridModule_NodeUptimeReported     []NodeUptimeReported //nolint:stylecheck,golint
 	TfgridModule_NodePublicConfigStored []NodePublicConfig   //nolint:stylecheck,golint
+	TfgridModule_PowerTargetChanged     []PowerTargetChanged //nolint:stylecheck,golint
+	TfgridModule_PowerStateChanged      []PowerStateChanged  //nolint:stylecheck,golint
+	// NodePublicConfigStored of runtimes before optionalPublicConfigSpec
+	TfgridModule_NodePublicConfigStoredV1 []NodePublicConfigV1 //nolint:stylecheck,golint
 
 	// entity events
 	TfgridModule_EntityStored  []EntityStored  //nolint:stylecheck,golint
@@ -308,15 +405,18 @@ type EventRecords struct {
 	TfgridModule_EntityDeleted []EntityDeleted //nolint:stylecheck,golint
 
 	// twin events
-	TfgridModule_TwinStored        []TwinStored        //nolint:stylecheck,golint
-	TfgridModule_TwinUpdated       []TwinStored        //nolint:stylecheck,golint
-	TfgridModule_TwinDeleted       []TwinDeleted       //nolint:stylecheck,golint
-	TfgridModule_TwinEntityStored  []TwinEntityStored  //nolint:stylecheck,golint
-	TfgridModule_TwinEntityRemoved []TwinEntityRemoved //nolint:stylecheck,golint
+	TfgridModule_TwinStored         []TwinStored         //nolint:stylecheck,golint
+	TfgridModule_TwinUpdated        []TwinStored         //nolint:stylecheck,golint
+	TfgridModule_TwinDeleted        []TwinDeleted        //nolint:stylecheck,golint
+	TfgridModule_TwinEntityStored   []TwinEntityStored   //nolint:stylecheck,golint
+	TfgridModule_TwinEntityRemoved  []TwinEntityRemoved  //nolint:stylecheck,golint
+	TfgridModule_TwinAccountBounded []TwinAccountBounded //nolint:stylecheck,golint
 
 	// policy events
 	TfgridModule_PricingPolicyStored []PricingPolicyStored //nolint:stylecheck,golint
 	TfgridModule_FarmingPolicyStored []FarmingPolicyStored //nolint:stylecheck,golint
+	// DEPRECATED: removed from the runtime
+	TfgridModule_CertificationCodeStored []CertificationCodeStored //nolint:stylecheck,golint
 
 	// other events
 	TfgridModule_FarmPayoutV2AddressRegistered []FarmPayoutV2AddressRegistered //nolint:stylecheck,golint
@@ -328,6 +428,7 @@ type EventRecords struct {
 	TfgridModule_FarmingPolicyUpdated          []FarmingPolicyUpdated          //nolint:stylecheck,golint
 	TfgridModule_FarmingPolicySet              []FarmingPolicySet              //nolint:stylecheck,golint
 	TfgridModule_FarmCertificationSet          []FarmCertificationSet          //nolint:stylecheck,golint
+	TfgridModule_ZosVersionUpdated             []ZosVersionUpdated             //nolint:stylecheck,golint
 
 	// burn module events
 	BurningModule_BurnTransactionCreated []BurnTransactionCreated //nolint:stylecheck,golint
@@ -388,4 +489,15 @@ type EventRecords struct {
 	Dao_Closed            []Closed            //nolint:stylecheck,golint
 	Dao_ClosedByCouncil   []ClosedByCouncil   //nolint:stylecheck,golint
 	Dao_CouncilMemberVeto []CouncilMemberVeto //nolint:stylecheck,golint
+
+	// batches
+	Utility_ItemCompleted []UtilityItemCompleted //nolint:stylecheck,golint
+	Utility_ItemFailed    []UtilityItemFailed    //nolint:stylecheck,golint
+
+	// transaction fees
+	Balances_Withdraw                     []BalancesWithdraw   //nolint:stylecheck,golint
+	TransactionPayment_TransactionFeePaid []TransactionFeePaid //nolint:stylecheck,golint
+
+	// Skipped are events that could not be decoded into one of the fields above
+	Skipped []RawEvent
 }
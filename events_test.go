@@ -0,0 +1,180 @@
+package substrate
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/centrifuge/go-substrate-rpc-client/v4/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventsRoundTrip(t *testing.T) {
+	phase := types.Phase{IsApplyExtrinsic: true, AsApplyExtrinsic: 3}
+	topics := []types.Hash{{1, 2, 3}}
+	account := AccountID{1, 2, 3, 4}
+
+	contract := Contract{
+		Versioned:  Versioned{Version: 3},
+		State:      ContractState{IsCreated: true},
+		ContractID: 10,
+		TwinID:     2,
+		ContractType: ContractType{
+			IsNodeContract: true,
+			NodeContract: NodeContract{
+				Node:           1,
+				DeploymentData: []byte("data"),
+				DeploymentHash: "hash",
+				PublicIPsCount: 1,
+				PublicIPs:      []PublicIP{{IP: "185.0.0.2/24", Gateway: "185.0.0.1", ContractID: 10}},
+			},
+		},
+	}
+
+	serviceContract := ServiceContract{
+		ServiceContractID:  1,
+		ServiceTwinID:      2,
+		ConsumerTwinID:     3,
+		BaseFee:            100,
+		VariableFee:        10,
+		Metadata:           "metadata",
+		AcceptedByService:  true,
+		AcceptedByConsumer: true,
+		LastBill:           1000,
+		State:              ServiceContractState{IsApprovedByBoth: true},
+	}
+
+	farmingPolicy := FarmingPolicy{
+		Versioned:         Versioned{Version: 1},
+		ID:                1,
+		Name:              "default",
+		CU:                1,
+		SU:                2,
+		NU:                3,
+		IPv4:              4,
+		MinimalUptime:     95,
+		PolicyCreated:     10,
+		PolicyEnd:         20,
+		Default:           true,
+		NodeCertification: NodeCertification{IsCertified: true},
+		FarmCertification: FarmCertification{isGold: true},
+	}
+
+	events := []interface{}{
+		// SmartContractModule
+		ContractCreated{Phase: phase, Contract: contract, Topics: topics},
+		ContractUpdated{Phase: phase, Contract: contract, Topics: topics},
+		NodeContractCanceled{Phase: phase, ContractID: 1, Node: 2, Twin: 3, Topics: topics},
+		NameContractCanceled{Phase: phase, ContractID: 1, Topics: topics},
+		RentContractCanceled{Phase: phase, ContractID: 1, Topics: topics},
+		IPsReserved{Phase: phase, ContractID: 1, IPs: []PublicIP{{IP: "ip", Gateway: "gw"}}, Topics: topics},
+		IPsFreed{Phase: phase, ContractID: 1, IPs: []string{"ip"}, Topics: topics},
+		ContractDeployed{Phase: phase, ContractID: 1, AccountID: account, Topics: topics},
+		ConsumptionReportReceived{Phase: phase, Consumption: Consumption{ContractID: 1, Timestamp: 2, CRU: 3}, Topics: topics},
+		NruConsumptionReportReceived{Phase: phase, Consumption: NruConsumption{ContractID: 1, Timestamp: 2, Window: 3, NRU: 4}, Topics: topics},
+		ContractBilled{Phase: phase, ContractBill: ContractBill{ContractID: 1, Timestamp: 2, DiscountLevel: DiscountLevel{IsGold: true}, AmountBilled: types.NewU128(*big.NewInt(100))}, Topics: topics},
+		TokensBurned{Phase: phase, ContractID: 1, Balance: types.NewU128(*big.NewInt(100)), Topics: topics},
+		UpdatedUsedResources{Phase: phase, ContractResources: ContractResources{ContractID: 1, Used: Resources{HRU: 1, SRU: 2, CRU: 3, MRU: 4}}, Topics: topics},
+		ContractGracePeriodStarted{Phase: phase, ContractID: 1, NodeID: 2, TwinID: 3, StartBlock: 4, Topics: topics},
+		ContractGracePeriodEnded{Phase: phase, ContractID: 1, NodeID: 2, TwinID: 3, Topics: topics},
+		NodeMarkAsDedicated{Phase: phase, NodeID: 1, Dedicated: true, Topics: topics},
+		SolutionProviderCreated{Phase: phase, SolutionProvider: SolutionProvider{
+			SolutionProviderID: 1,
+			Providers:          []Provider{{Who: account, Take: 10}},
+			Description:        "description",
+			Link:               "link",
+		}, Topics: topics},
+		SolutionProviderApproved{Phase: phase, SolutionProviderID: 1, Approved: true, Topics: topics},
+		BillingFrequencyChanged{Phase: phase, Frequency: 600, Topics: topics},
+		NodeExtraFeeSet{Phase: phase, NodeID: 1, ExtraFee: 2, Topics: topics},
+		ServiceContractEvent{Phase: phase, ServiceContract: serviceContract, Topics: topics},
+		ServiceContractCanceled{Phase: phase, ServiceContractID: 1, Cause: Cause{IsOutOfFunds: true}, Topics: topics},
+		ServiceContractBilled{Phase: phase, ServiceContract: serviceContract, Bill: ServiceContractBill{VariableAmount: 1, Window: 2, Metadata: "bill"}, Amount: types.NewU128(*big.NewInt(100)), Topics: topics},
+
+		// TfgridModule
+		FarmStored{Phase: phase, Farm: Farm{
+			ID:                1,
+			Name:              "farm",
+			TwinID:            2,
+			PricingPolicyID:   1,
+			CertificationType: FarmCertification{isNotCertified: true},
+			PublicIPs:         []PublicIP{{IP: "ip", Gateway: "gw"}},
+			FarmingPoliciesLimit: OptionFarmingPolicyLimit{HasValue: true, AsValue: FarmingPolicyLimit{
+				FarmingPolicyID: 1,
+				Cu:              types.NewOptionU64(10),
+				NodeCount:       types.NewOptionU32(2),
+			}},
+		}, Topics: topics},
+		FarmDeleted{Phase: phase, Farm: 1, Topics: topics},
+		NodeStored{Phase: phase, Node: Node{
+			ID:            1,
+			FarmID:        2,
+			TwinID:        3,
+			Resources:     Resources{HRU: 1, SRU: 2, CRU: 3, MRU: 4},
+			Location:      Location{Longitude: "1", Latitude: "2"},
+			Country:       "Egypt",
+			City:          "Cairo",
+			PublicConfig:  OptionPublicConfig{HasValue: true, AsValue: PublicConfig{IPv4: "ip4", GWv4: "gw4"}},
+			Interfaces:    []Interface{{Name: "zos", Mac: "mac", IPs: []string{"ip"}}},
+			Certification: NodeCertification{IsDiy: true},
+			BoardSerial:   "serial",
+		}, Topics: topics},
+		NodeDeleted{Phase: phase, Node: 1, Topics: topics},
+		NodeUptimeReported{Phase: phase, Node: 1, Timestamp: 2, Uptime: 3, Topics: topics},
+		NodePublicConfig{Phase: phase, Node: 1, Config: OptionPublicConfig{HasValue: true, AsValue: PublicConfig{IPv4: "ip4"}}, Topics: topics},
+		NodePublicConfig{Phase: phase, Node: 1, Topics: topics},
+		NodePublicConfigV1{Phase: phase, Node: 1, Config: PublicConfig{IPv4: "ip4"}, Topics: topics},
+		PowerTargetChanged{Phase: phase, Farm: 1, Node: 2, Target: Power{IsDown: true}, Topics: topics},
+		PowerStateChanged{Phase: phase, Farm: 1, Node: 2, State: PowerState{IsDown: true, AsDown: 100}, Topics: topics},
+		PowerStateChanged{Phase: phase, Farm: 1, Node: 2, State: PowerState{IsUp: true}, Topics: topics},
+		EntityStored{Phase: phase, Entity: Entity{ID: 1, Name: "entity", Account: account}, Topics: topics},
+		EntityDeleted{Phase: phase, Entity: 1, Topics: topics},
+		TwinStored{Phase: phase, Twin: Twin{ID: 1, Account: account, IP: "::1", Entities: []EntityProof{{EntityID: 1, Signature: "sig"}}}, Topics: topics},
+		TwinDeleted{Phase: phase, Twin: 1, Topics: topics},
+		TwinEntityStored{Phase: phase, Twin: 1, Entity: 2, Signature: []byte("sig"), Topics: topics},
+		TwinEntityRemoved{Phase: phase, Twin: 1, Entity: 2, Topics: topics},
+		TwinAccountBounded{Phase: phase, Twin: 1, Account: account, Topics: topics},
+		PricingPolicyStored{Phase: phase, Policy: PricingPolicy{ID: 1, Name: "policy", SU: Policy{Value: 1, Unit: 2}, FoundationAccount: account}, Topics: topics},
+		FarmingPolicyStored{Phase: phase, Policy: farmingPolicy, Topics: topics},
+		FarmingPolicyUpdated{Phase: phase, FarmingPolicy: farmingPolicy, Topics: topics},
+		CertificationCodeStored{Phase: phase, Codes: CertificationCodes{ID: 1, Name: "code", Description: "description", CertificationCodeType: 1}, Topics: topics},
+		FarmPayoutV2AddressRegistered{Phase: phase, Farm: 1, Address: "address", Topics: topics},
+		FarmMarkedAsDedicated{Phase: phase, Farm: 1, Topics: topics},
+		ConnectionPriceSet{Phase: phase, Price: 80, Topics: topics},
+		NodeCertificationSet{Phase: phase, NodeID: 1, Certification: NodeCertification{IsCertified: true}, Topics: topics},
+		NodeCertifierAdded{Phase: phase, Address: account, Topics: topics},
+		NodeCertifierRemoved{Phase: phase, Address: account, Topics: topics},
+		FarmingPolicySet{Phase: phase, Farm: 1, FarmingPolicy: OptionFarmingPolicyLimit{HasValue: true, AsValue: FarmingPolicyLimit{FarmingPolicyID: 1}}, Topics: topics},
+		FarmCertificationSet{Phase: phase, Farm: 1, Certification: FarmCertification{isGold: true}, Topics: topics},
+		ZosVersionUpdated{Phase: phase, Version: "3.0", Topics: topics},
+	}
+
+	for _, event := range events {
+		typ := reflect.TypeOf(event)
+		t.Run(typ.Name(), func(t *testing.T) {
+			data, err := types.EncodeToBytes(event)
+			require.NoError(t, err)
+
+			decoded := reflect.New(typ)
+			require.NoError(t, types.DecodeFromBytes(data, decoded.Interface()))
+			require.Equal(t, event, decoded.Elem().Interface())
+		})
+	}
+}
+
+func TestEventRecordsCoverage(t *testing.T) {
+	// every field must be a slice of events so the decoder can use it
+	typ := reflect.TypeOf(EventRecords{})
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.Anonymous || field.Name == "Skipped" {
+			continue
+		}
+
+		require.Equal(t, reflect.Slice, field.Type.Kind(), field.Name)
+		_, ok := field.Type.Elem().FieldByName("Phase")
+		require.True(t, ok, field.Name)
+		_, ok = field.Type.Elem().FieldByName("Topics")
+		require.True(t, ok, field.Name)
+	}
+}
@@ -0,0 +1,181 @@
+package substrate
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+
+	"github.com/centrifuge/go-substrate-rpc-client/v4/scale"
+	"github.com/centrifuge/go-substrate-rpc-client/v4/types"
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrInvalidTransaction is returned by DryRun if the extrinsic would
+	// be rejected by the transaction pool
+	ErrInvalidTransaction = fmt.Errorf("invalid transaction")
+)
+
+// https://github.com/paritytech/substrate/blob/polkadot-v0.9.16/primitives/runtime/src/transaction_validity.rs
+var (
+	invalidTransactionErrors = []string{
+		"Call",
+		"Payment",
+		"Future",
+		"Stale",
+		"BadProof",
+		"AncientBirthBlock",
+		"ExhaustsResources",
+		"Custom",
+		"BadMandatory",
+		"MandatoryDispatch",
+		"BadSigner",
+	}
+
+	unknownTransactionErrors = []string{
+		"CannotLookup",
+		"NoUnsignedValidator",
+		"Custom",
+	}
+)
+
+// EstimateFee estimates the fee the identity will pay for the call. The call is
+// signed but never submitted, so no nonce is consumed.
+func (s *Substrate) EstimateFee(identity Identity, call types.Call, opts ...CallOption) (*big.Int, error) {
+	cl, meta, err := s.getClient()
+	if err != nil {
+		return nil, err
+	}
+
+	ext, err := s.unsubmitted(cl, meta, identity, call, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var info struct {
+		PartialFee string `json:"partialFee"`
+	}
+
+	if err := cl.Client.Call(&info, "payment_queryInfo", ext); err != nil {
+		return nil, errors.Wrap(err, "failed to query fee info")
+	}
+
+	fee, ok := big.NewInt(0).SetString(info.PartialFee, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid fee value '%s'", info.PartialFee)
+	}
+
+	return fee, nil
+}
+
+// DryRun checks if the call would succeed without submitting it. It returns
+// a *DispatchError if the call would fail on chain, or ErrInvalidTransaction
+// if the extrinsic would not be accepted at all.
+func (s *Substrate) DryRun(identity Identity, call types.Call, opts ...CallOption) error {
+	cl, meta, err := s.getClient()
+	if err != nil {
+		return err
+	}
+
+	ext, err := s.unsubmitted(cl, meta, identity, call, opts)
+	if err != nil {
+		return err
+	}
+
+	var result string
+	if err := cl.Client.Call(&result, "system_dryRun", ext); err != nil {
+		return errors.Wrap(err, "failed to dry run extrinsic")
+	}
+
+	data, err := types.HexDecodeString(result)
+	if err != nil {
+		return errors.Wrap(err, "invalid dry run result")
+	}
+
+	return applyExtrinsicResult(meta, data)
+}
+
+// unsubmitted creates a signed extrinsic that is not meant to be submitted,
+// encoded as hex.
+func (s *Substrate) unsubmitted(cl Conn, meta Meta, identity Identity, call types.Call, opts []CallOption) (string, error) {
+	// the nonce is not tracked since the extrinsic is never submitted
+	nonce, err := s.nextIndex(cl, meta, identity)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get nonce")
+	}
+
+	ext, err := s.extrinsic(cl, identity, call, nonce, s.callOptions(opts))
+	if err != nil {
+		return "", err
+	}
+
+	return types.EncodeToHexString(ext)
+}
+
+// applyExtrinsicResult decodes an ApplyExtrinsicResult which is a
+// Result<Result<(), DispatchError>, TransactionValidityError>
+func applyExtrinsicResult(meta Meta, data []byte) error {
+	decoder := scale.NewDecoder(bytes.NewReader(data))
+
+	valid, err := decoder.ReadOneByte()
+	if err != nil {
+		return errors.Wrap(err, "failed to decode apply result")
+	}
+
+	if valid != 0 {
+		return transactionValidityError(decoder)
+	}
+
+	ok, err := decoder.ReadOneByte()
+	if err != nil {
+		return errors.Wrap(err, "failed to decode dispatch result")
+	}
+
+	if ok == 0 {
+		return nil
+	}
+
+	variant, err := decoder.ReadOneByte()
+	if err != nil {
+		return errors.Wrap(err, "failed to decode dispatch error")
+	}
+
+	if variant != dispatchErrorModule {
+		// types.DispatchError can't decode other variants
+		return otherDispatchError(variant)
+	}
+
+	de := types.DispatchError{HasModule: true}
+	if err := decoder.Decode(&de.Module); err != nil {
+		return errors.Wrap(err, "failed to decode dispatch error")
+	}
+
+	if err := decoder.Decode(&de.Error); err != nil {
+		return errors.Wrap(err, "failed to decode dispatch error")
+	}
+
+	return newDispatchError(meta, de)
+}
+
+func transactionValidityError(decoder *scale.Decoder) error {
+	kind, err := decoder.ReadOneByte()
+	if err != nil {
+		return errors.Wrap(err, "failed to decode transaction validity error")
+	}
+
+	names := invalidTransactionErrors
+	if kind != 0 {
+		names = unknownTransactionErrors
+	}
+
+	index, err := decoder.ReadOneByte()
+	if err != nil {
+		return errors.Wrap(err, "failed to decode transaction validity error")
+	}
+
+	if int(index) >= len(names) {
+		return errors.Wrapf(ErrInvalidTransaction, "error with code %d", index)
+	}
+
+	return errors.Wrap(ErrInvalidTransaction, names[index])
+}
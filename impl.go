@@ -1,6 +1,7 @@
 package substrate
 
 import (
+	"context"
 	"fmt"
 	"math/rand"
 	"sync"
@@ -8,6 +9,7 @@ import (
 
 	"github.com/cenkalti/backoff"
 	gsrpc "github.com/centrifuge/go-substrate-rpc-client/v4"
+	"github.com/centrifuge/go-substrate-rpc-client/v4/config"
 	"github.com/centrifuge/go-substrate-rpc-client/v4/types"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
@@ -18,6 +20,13 @@ const (
 	// be behind before we don't accept it. block time is 6 seconds, so
 	// right now we only allow 2 blocks delay
 	acceptableDelay = 2 * 6 * time.Second
+
+	// defaultPoolSize is the default max number of idle connections
+	// kept per endpoint
+	defaultPoolSize = 5
+	// defaultIdleTimeout is the default amount of time an idle connection
+	// is kept in the pool before it's closed
+	defaultIdleTimeout = 5 * time.Minute
 )
 
 var (
@@ -39,7 +48,66 @@ type Meta = *types.Metadata
 
 type Manager interface {
 	Raw() (Conn, Meta, error)
+	// RawContext is like Raw but stops retrying to connect once ctx is done
+	RawContext(ctx context.Context) (Conn, Meta, error)
 	Substrate() (*Substrate, error)
+	// SubstrateContext is like Substrate but the returned client
+	// is bound to ctx (see Substrate.WithContext)
+	SubstrateContext(ctx context.Context) (*Substrate, error)
+	// Close closes all idle connections in the pool. Connections that
+	// are still in use are closed once they are released.
+	Close()
+}
+
+// ManagerOption configures a manager
+type ManagerOption func(*mgrImpl)
+
+// WithPoolSize sets the max number of idle connections kept per endpoint.
+// A size of 0 disables connection reuse.
+func WithPoolSize(size int) ManagerOption {
+	return func(p *mgrImpl) {
+		p.size = size
+	}
+}
+
+// WithIdleTimeout sets how long an idle connection is kept in the pool
+// before it's closed
+func WithIdleTimeout(timeout time.Duration) ManagerOption {
+	return func(p *mgrImpl) {
+		p.timeout = timeout
+	}
+}
+
+// WithDialer sets the dialer used to open connections to the endpoints,
+// by default the endpoints are dialed as websocket urls
+func WithDialer(dialer Dialer) ManagerOption {
+	return func(p *mgrImpl) {
+		p.dialer = dialer
+	}
+}
+
+// WithAcceptableDelay sets how far behind the clock the last block of a node
+// can be before the node is refused. A delay of 0 disables the check which
+// is needed to replay recorded sessions.
+func WithAcceptableDelay(delay time.Duration) ManagerOption {
+	return func(p *mgrImpl) {
+		p.delay = delay
+	}
+}
+
+// WithMetrics records the metrics of the manager and its clients
+func WithMetrics(metrics *Metrics) ManagerOption {
+	return func(p *mgrImpl) {
+		p.metrics = metrics
+	}
+}
+
+// poolConn is an idle connection in the pool
+type poolConn struct {
+	cl   Conn
+	meta Meta
+	// since is the time the connection was released to the pool
+	since time.Time
 }
 
 type mgrImpl struct {
@@ -47,9 +115,36 @@ type mgrImpl struct {
 
 	r int
 	m sync.Mutex
+
+	size    int
+	timeout time.Duration
+	idle    map[string][]poolConn
+	closed  bool
+
+	// dialer is nil if connections are dialed directly
+	dialer Dialer
+	delay  time.Duration
+	// metrics is nil if not instrumented
+	metrics *Metrics
+
+	nonces *nonceTracker
+	metas  *metaCache
+	times  *timeCache
+
+	// watch starts watching for runtime upgrades once
+	watch sync.Once
+	// ctx is cancelled when the manager is closed
+	ctx    context.Context
+	cancel context.CancelFunc
 }
 
+// NewManager creates a new manager with default pool options
 func NewManager(url ...string) Manager {
+	return NewManagerWithOptions(url)
+}
+
+// NewManagerWithOptions creates a new manager for the given urls
+func NewManagerWithOptions(url []string, opts ...ManagerOption) Manager {
 	if len(url) == 0 {
 		panic("at least one url is required")
 	}
@@ -62,10 +157,25 @@ func NewManager(url ...string) Manager {
 		url[i], url[j] = url[j], url[i]
 	})
 
-	return &mgrImpl{
-		urls: url,
-		r:    rand.Intn(len(url)), // start with random url, then roundrobin
+	mgr := &mgrImpl{
+		urls:    url,
+		r:       rand.Intn(len(url)), // start with random url, then roundrobin
+		size:    defaultPoolSize,
+		timeout: defaultIdleTimeout,
+		delay:   acceptableDelay,
+		idle:    make(map[string][]poolConn),
+		nonces:  newNonceTracker(),
+		metas:   newMetaCache(),
+		times:   newTimeCache(),
+	}
+
+	mgr.ctx, mgr.cancel = context.WithCancel(context.Background())
+
+	for _, opt := range opts {
+		opt(mgr)
 	}
+
+	return mgr
 }
 
 // endpoint return the next endpoint to use
@@ -81,101 +191,400 @@ func (p *mgrImpl) endpoint() string {
 
 // Substrate return a new wrapped substrate connection
 // the connection must be closed after you are done using it
+// so it can be returned to the pool
 func (p *mgrImpl) Substrate() (*Substrate, error) {
-	cl, meta, err := p.Raw()
+	return p.substrate(context.Background())
+}
+
+// SubstrateContext implements Manager
+func (p *mgrImpl) SubstrateContext(ctx context.Context) (*Substrate, error) {
+	sub, err := p.substrate(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	return newSubstrate(cl, meta, p.put)
+	sub.ctx = ctx
+	return sub, nil
+}
+
+func (p *mgrImpl) substrate(ctx context.Context) (*Substrate, error) {
+	conn, ok := p.get(ctx)
+	p.metrics.poolRequest(ok)
+	if ok {
+		return newSubstrate(conn.cl, conn.meta, p, p.nonces, p.metas, p.times, p.metrics, p.put)
+	}
+
+	cl, meta, err := p.RawContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return newSubstrate(cl, meta, p, p.nonces, p.metas, p.times, p.metrics, p.put)
+}
+
+// get returns a healthy idle connection from the pool if one
+// is available. Broken, expired or lagging connections are evicted.
+func (p *mgrImpl) get(ctx context.Context) (poolConn, bool) {
+	for ctx.Err() == nil {
+		conn, ok := p.pop()
+		if !ok {
+			return conn, false
+		}
+
+		if err := p.check(withContext(ctx, conn.cl), conn.meta); err != nil {
+			log.Debug().Err(err).Str("url", conn.cl.Client.URL()).Msg("evicting pooled connection")
+			p.metrics.evict(conn.cl.Client.URL(), evictionUnhealthy)
+			conn.cl.Client.Close()
+			continue
+		}
+
+		return conn, true
+	}
+
+	return poolConn{}, false
+}
+
+// pop takes the most recently used idle connection out of the pool, starting
+// from the next endpoint in roundrobin fashion. Expired connections
+// are closed on the way.
+func (p *mgrImpl) pop() (poolConn, bool) {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	for range p.urls {
+		endpoint := p.endpoint()
+		conns := p.expire(p.idle[endpoint])
+		if len(conns) == 0 {
+			delete(p.idle, endpoint)
+			p.metrics.setIdle(endpoint, 0)
+			continue
+		}
+
+		conn := conns[len(conns)-1]
+		p.idle[endpoint] = conns[:len(conns)-1]
+		p.metrics.setIdle(endpoint, len(p.idle[endpoint]))
+		return conn, true
+	}
+
+	return poolConn{}, false
+}
+
+// expire closes and drops connections that has been idle longer than the
+// idle timeout. need to be called while lock is acquired.
+func (p *mgrImpl) expire(conns []poolConn) []poolConn {
+	// connections are appended on release so the oldest are first
+	i := 0
+	for ; i < len(conns) && time.Since(conns[i].since) > p.timeout; i++ {
+		p.metrics.evict(conns[i].cl.Client.URL(), evictionExpired)
+		conns[i].cl.Client.Close()
+	}
+
+	return conns[i:]
+}
+
+// Close implements Manager
+func (p *mgrImpl) Close() {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	p.closed = true
+	p.cancel()
+	for endpoint, conns := range p.idle {
+		for _, conn := range conns {
+			conn.cl.Client.Close()
+		}
+		delete(p.idle, endpoint)
+		p.metrics.setIdle(endpoint, 0)
+	}
 }
 
 // Raw returns a RPC substrate client. plus meta. The returned connection
 // is not tracked by the pool, nor reusable. It's the caller responsibility
 // to close the connection when done
 func (p *mgrImpl) Raw() (Conn, Meta, error) {
-	// right now this pool implementation just tests the connection
-	// makes sure that it is still active, otherwise, tries again
-	// until the connection is restored.
-	// A better pool implementation can be done later were multiple connections
-	// can be handled
-	// TODO: thread safety!
-	p.m.Lock()
-	defer p.m.Unlock()
+	return p.RawContext(context.Background())
+}
 
-	boff := backoff.WithMaxRetries(
-		backoff.NewConstantBackOff(200*time.Millisecond),
-		2*uint64(len(p.urls)),
+// RawContext implements Manager
+func (p *mgrImpl) RawContext(ctx context.Context) (Conn, Meta, error) {
+	// dials a new connection and makes sure that it is active
+	// and up to date, otherwise, tries the next endpoint.
+	// the lock is only held while picking the endpoint so
+	// releasing connections to the pool is not blocked by dialing
+	boff := backoff.WithContext(
+		backoff.WithMaxRetries(
+			backoff.NewConstantBackOff(200*time.Millisecond),
+			2*uint64(len(p.urls)),
+		),
+		ctx,
 	)
 
 	var (
-		cl   *gsrpc.SubstrateAPI
-		meta *types.Metadata
+		cl   Conn
+		meta Meta
 		err  error
 	)
 
 	err = backoff.RetryNotify(func() error {
+		p.m.Lock()
 		endpoint := p.endpoint()
+		p.m.Unlock()
+
 		log.Debug().Str("url", endpoint).Msg("connecting")
-		cl, err = gsrpc.NewSubstrateAPI(endpoint)
+		cl, err = p.dial(ctx, endpoint)
 		if err != nil {
+			p.metrics.endpoint(endpoint, endpointUnreachable)
 			return errors.Wrapf(err, "error connecting to substrate at '%s'", endpoint)
 		}
 
-		meta, err = cl.RPC.State.GetMetadataLatest()
+		// the metadata is only fetched if the runtime version is not known yet
+		meta, err = p.metas.current(withContext(ctx, cl))
 		if err != nil {
+			p.metrics.endpoint(endpoint, endpointUnhealthy)
+			cl.Client.Close()
 			return errors.Wrapf(err, "error getting latest metadata at '%s'", endpoint)
 		}
 
-		t, err := getTime(cl, meta)
-		if err != nil {
-			return errors.Wrapf(err, "error getting node time at '%s'", endpoint)
-		}
-
-		if time.Since(t) > acceptableDelay {
-			return fmt.Errorf("node '%s' is behind acceptable delay with timestamp '%s'", endpoint, t)
+		if err := p.check(withContext(ctx, cl), meta); err != nil {
+			cl.Client.Close()
+			return err
 		}
 
+		p.metrics.endpoint(endpoint, endpointConnected)
 		return nil
 
 	}, boff, func(err error, d time.Duration) {
 		log.Error().Err(err).Msg("failed to connect to endpoint, retrying")
 	})
 
+	if err == nil {
+		p.watch.Do(func() {
+			go p.watchRuntime(p.ctx)
+		})
+	}
+
 	return cl, meta, err
 }
 
-// TODO: implement reusable connections instead of
-// closing the connection.
+// watchRuntime follows runtime upgrades so all clients of the manager
+// use the metadata of the latest runtime version
+func (p *mgrImpl) watchRuntime(ctx context.Context) {
+	exp := backoff.NewExponentialBackOff()
+	exp.MaxElapsedTime = 0 // retry forever
+	exp.MaxInterval = time.Minute
+
+	for {
+		err := p.followRuntime(ctx, exp)
+		if ctx.Err() != nil {
+			return
+		}
+
+		wait := exp.NextBackOff()
+		log.Debug().Err(err).Dur("retry-in", wait).Msg("runtime version subscription interrupted")
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (p *mgrImpl) followRuntime(ctx context.Context, exp backoff.BackOff) error {
+	cl, _, err := p.RawContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer cl.Client.Close()
+
+	versions := make(chan types.RuntimeVersion)
+	sub, err := subscribe(cl, versions, "state", "subscribeRuntimeVersion", "unsubscribeRuntimeVersion", "runtimeVersion")
+	if err != nil {
+		return errors.Wrap(err, "failed to subscribe to runtime version")
+	}
+	defer sub.Unsubscribe()
+
+	exp.Reset()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-sub.Err():
+			return err
+		case version := <-versions:
+			if _, err := p.metas.upgrade(withContext(ctx, cl), uint32(version.SpecVersion)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// dial opens a new connection to the given endpoint. The dial is bounded by
+// the default gsrpc dial timeout so an unresponsive endpoint fails over to
+// the next one even if ctx has no deadline.
+func (p *mgrImpl) dial(ctx context.Context, endpoint string) (Conn, error) {
+	ctx, cancel := context.WithTimeout(ctx, config.Default().DialTimeout)
+	defer cancel()
+
+	if p.dialer == nil {
+		cl, err := dial(ctx, endpoint)
+		if err != nil {
+			return nil, err
+		}
+
+		return newConn(p.metrics.client(cl)), nil
+	}
+
+	t, err := p.dialer(ctx, endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	cl, err := newTransportClient(ctx, t, endpoint)
+	if err != nil {
+		t.Close()
+		return nil, err
+	}
+
+	return newConn(p.metrics.client(cl)), nil
+}
+
+// check makes sure the connection is alive and that the node
+// is not behind the acceptable delay
+func (p *mgrImpl) check(cl Conn, meta Meta) error {
+	endpoint := cl.Client.URL()
+	t, err := getTime(cl, meta)
+	if err != nil {
+		p.metrics.endpoint(endpoint, endpointUnhealthy)
+		return errors.Wrapf(err, "error getting node time at '%s'", endpoint)
+	}
+
+	if p.delay > 0 && time.Since(t) > p.delay {
+		p.metrics.endpoint(endpoint, endpointBehind)
+		return fmt.Errorf("node '%s' is behind acceptable delay with timestamp '%s'", endpoint, t)
+	}
+
+	return nil
+}
+
+// put releases the connection back to the pool, the connection
+// is closed instead if the pool for that endpoint is full.
 func (p *mgrImpl) put(cl *Substrate) {
-	// naive put implementation for now
-	// we just immediately kill the connection
-	if cl.cl != nil {
-		cl.cl.Client.Close()
+	if cl.cl == nil {
+		// already released
+		return
 	}
+
+	conn := poolConn{cl: cl.cl, meta: cl.meta, since: time.Now()}
 	cl.cl = nil
 	cl.meta = nil
+
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	endpoint := conn.cl.Client.URL()
+	conns := p.expire(p.idle[endpoint])
+	if p.closed || len(conns) >= p.size {
+		if !p.closed {
+			p.metrics.evict(endpoint, evictionFull)
+		}
+		conn.cl.Client.Close()
+		p.idle[endpoint] = conns
+		p.metrics.setIdle(endpoint, len(conns))
+		return
+	}
+
+	p.idle[endpoint] = append(conns, conn)
+	p.metrics.setIdle(endpoint, len(p.idle[endpoint]))
 }
 
 // Substrate client
 type Substrate struct {
 	cl   Conn
 	meta Meta
+	ctx  context.Context
+
+	// mgr is the manager that created the client, subscriptions
+	// reconnect through it
+	mgr Manager
+	// nonces is shared between all clients of the same manager
+	nonces *nonceTracker
+	// opts are default call options
+	opts []CallOption
+	// metas is shared between all clients of the same manager
+	metas *metaCache
+	// times is shared between all clients of the same manager
+	times *timeCache
+	// metrics is nil if the manager is not instrumented
+	metrics *Metrics
 
 	close func(s *Substrate)
 }
 
 // NewSubstrate creates a substrate client
-func newSubstrate(cl Conn, meta Meta, close func(*Substrate)) (*Substrate, error) {
-	return &Substrate{cl: cl, meta: meta, close: close}, nil
+func newSubstrate(cl Conn, meta Meta, mgr Manager, nonces *nonceTracker, metas *metaCache, times *timeCache, metrics *Metrics, close func(*Substrate)) (*Substrate, error) {
+	if metas == nil {
+		metas = newMetaCache()
+	}
+
+	if times == nil {
+		times = newTimeCache()
+	}
+
+	return &Substrate{cl: cl, meta: meta, mgr: mgr, nonces: nonces, metas: metas, times: times, metrics: metrics, close: close}, nil
 }
 
 func (s *Substrate) Close() {
 	s.close(s)
 }
 
+// WithContext returns a shallow copy of s where all chain calls are bound to ctx.
+// Cancelling ctx aborts pending RPC reads, waiting for extrinsics and retries.
+// The copy shares the connection with s, closing the copy is a no-op so only
+// s must be closed when done.
+func (s *Substrate) WithContext(ctx context.Context) *Substrate {
+	if ctx == nil {
+		panic("nil context")
+	}
+
+	cp := s.derive()
+	cp.ctx = ctx
+	return cp
+}
+
+// derive returns a shallow copy of s that can't close the connection
+func (s *Substrate) derive() *Substrate {
+	cp := *s
+	cp.close = func(*Substrate) {}
+	return &cp
+}
+
+// Context returns the context the client is bound to. It defaults
+// to the background context.
+func (s *Substrate) Context() context.Context {
+	if s.ctx != nil {
+		return s.ctx
+	}
+
+	return context.Background()
+}
+
 func (s *Substrate) getClient() (Conn, Meta, error) {
-	return s.cl, s.meta, nil
+	if s.cl == nil {
+		return nil, nil, fmt.Errorf("substrate client is closed")
+	}
+
+	meta := s.meta
+	if latest := s.metas.last(); latest != nil {
+		// the runtime was upgraded since the connection was made
+		meta = latest
+	}
+
+	if s.ctx != nil {
+		return withContext(s.ctx, s.cl), meta, nil
+	}
+
+	return s.cl, meta, nil
 }
 
 func (s *Substrate) GetClient() (Conn, Meta, error) {
@@ -211,10 +620,36 @@ func getTime(cl Conn, meta Meta) (t time.Time, err error) {
 		return t, errors.Wrap(err, "failed to lookup entity")
 	}
 
-	var stamp types.Moment
-	if err := types.DecodeFromBytes(*raw, &stamp); err != nil {
+	return decodeTime(*raw)
+}
+
+// getTimeAt gets the timestamp of the given block
+func getTimeAt(cl Conn, meta Meta, block types.Hash) (t time.Time, err error) {
+	key, err := types.CreateStorageKey(meta, "Timestamp", "Now", nil)
+	if err != nil {
+		return t, errors.Wrap(err, "failed to create substrate query key")
+	}
+
+	raw, err := cl.RPC.State.GetStorageRaw(key, block)
+	if err != nil {
+		return t, errors.Wrap(err, "failed to lookup entity")
+	}
+
+	if len(*raw) == 0 {
+		// the genesis block has no timestamp
+		return t, errors.Wrapf(ErrNotFound, "no timestamp at block '%s'", block.Hex())
+	}
+
+	return decodeTime(*raw)
+}
+
+// decodeTime decodes the Timestamp.Now milliseconds. types.Moment is not
+// used since it scales the milliseconds remainder to seconds.
+func decodeTime(raw types.StorageDataRaw) (t time.Time, err error) {
+	var ms uint64
+	if err := types.DecodeFromBytes(raw, &ms); err != nil {
 		return t, errors.Wrap(err, "failed to get node time")
 	}
 
-	return stamp.Time, nil
+	return time.Unix(0, int64(ms)*int64(time.Millisecond)), nil
 }
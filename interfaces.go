@@ -0,0 +1,131 @@
+package substrate
+
+import (
+	"context"
+	"math/big"
+	"net"
+	"time"
+
+	"github.com/centrifuge/go-substrate-rpc-client/v4/types"
+)
+
+// The interfaces below group the methods of Substrate by role so code can
+// depend on the smallest set of methods it uses, and be tested with a fake
+// or wrapped with decorators. The substratemock package has a mock of
+// all of them, regenerate it with `go generate ./substratemock` after
+// changing an interface.
+
+// TwinManager reads and manages twins
+type TwinManager interface {
+	GetTwin(id uint32) (*Twin, error)
+	GetTwinByPubKey(pk []byte) (uint32, error)
+	CreateTwin(identity Identity, ip net.IP) (uint32, error)
+	UpdateTwin(identity Identity, ip net.IP) (uint32, error)
+	AcceptTermsAndConditions(identity Identity, documentLink string, documentHash string) error
+	SignedTermsAndConditions(account AccountID) ([]TermsAndConditions, error)
+}
+
+// NodeReader reads nodes and the farms they belong to
+type NodeReader interface {
+	GetNode(id uint32) (*Node, error)
+	GetNodeByTwinID(twin uint32) (uint32, error)
+	GetLastNodeID() (uint32, error)
+	ScanNodes(ctx context.Context, from, to uint32) (<-chan ScannedNode, error)
+	GetFarm(id uint32) (*Farm, error)
+	GetEntity(id uint32) (*Entity, error)
+}
+
+// NodeWriter registers and updates nodes
+type NodeWriter interface {
+	CreateNode(identity Identity, node Node) (uint32, error)
+	UpdateNode(identity Identity, node Node) (uint32, error)
+	UpdateNodeUptime(identity Identity, uptime uint64) (types.Hash, error)
+	SetNodeCertificate(sudo Identity, id uint32, cert NodeCertification) error
+}
+
+// ContractReader reads contracts
+type ContractReader interface {
+	GetContract(id uint64) (*Contract, error)
+	GetContractWithHash(node uint32, hash string) (uint64, error)
+	GetNodeRentContract(node uint32) (uint64, error)
+	GetContractIDByNameRegistration(name string) (uint64, error)
+	GetNodeContracts(node uint32) ([]types.U64, error)
+}
+
+// ContractWriter creates, updates and cancels contracts and reports
+// their consumption
+type ContractWriter interface {
+	CreateNodeContract(identity Identity, node uint32, body []byte, hash string, publicIPs uint32) (uint64, error)
+	CreateNameContract(identity Identity, name string) (uint64, error)
+	CreateRentContract(identity Identity, node uint32) (uint64, error)
+	UpdateNodeContract(identity Identity, contract uint64, body []byte, hash string) (uint64, error)
+	CancelContract(identity Identity, contract uint64) error
+	SetContractConsumption(identity Identity, resources ...ContractResources) error
+	Report(identity Identity, consumptions []NruConsumption) (types.Hash, error)
+}
+
+// BridgeValidator is used by the bridge validators to mint, burn and refund
+type BridgeValidator interface {
+	IsValidator(identity Identity) (bool, error)
+	IsMintedAlready(identity Identity, mintTxID string) (bool, error)
+	ProposeOrVoteMintTransaction(identity Identity, txID string, target AccountID, amount *big.Int) (*types.Call, error)
+	IsBurnedAlready(identity Identity, burnTransactionID types.U64) (bool, error)
+	GetBurnTransaction(identity Identity, burnTransactionID types.U64) (*BurnTransaction, error)
+	ProposeBurnTransactionOrAddSig(identity Identity, txID uint64, target string, amount *big.Int, signature string, stellarAddress string, sequenceNumber uint64) (*types.Call, error)
+	SetBurnTransactionExecuted(identity Identity, txID uint64) (*types.Call, error)
+	IsRefundedAlready(identity Identity, txHash string) (bool, error)
+	GetRefundTransaction(identity Identity, txHash string) (*RefundTransaction, error)
+	CreateRefundTransactionOrAddSig(identity Identity, txHash string, target string, amount int64, signature string, stellarAddress string, sequenceNumber uint64) (*types.Call, error)
+	SetRefundTransactionExecuted(identity Identity, txHash string) (*types.Call, error)
+}
+
+// EventSource reads blocks and their events
+type EventSource interface {
+	GetCurrentHeight() (uint32, error)
+	GetBlock(block types.Hash) (*types.SignedBlock, error)
+	GetEventsForBlock(height uint32) (*EventRecords, error)
+	GetEventsForBlockRange(start uint32, end uint32) ([]BlockEvents, error)
+	GetBlockInfo(hash types.Hash) (*BlockInfo, error)
+	GetBlockInfoAt(number uint32) (*BlockInfo, error)
+	IterateBlocks(from, to uint32, fn func(block *BlockInfo) error) error
+	SubscribeEvents(ctx context.Context, from uint32, finalized bool) (<-chan BlockEvents, error)
+}
+
+// Clock converts between block heights and chain time
+type Clock interface {
+	Time() (time.Time, error)
+	BlockTime(height uint32) (time.Time, error)
+	BlockAt(t time.Time) (uint32, error)
+}
+
+// AccountManager reads and activates accounts
+type AccountManager interface {
+	EnsureAccount(identity Identity, activationURL, termsAndConditionsLink, termsAndConditionsHash string) (types.AccountInfo, error)
+	GetAccount(identity Identity) (types.AccountInfo, error)
+	GetDepositFee(identity Identity) (int64, error)
+}
+
+// Transactor executes arbitrary calls
+type Transactor interface {
+	Batch(identity Identity, calls []types.Call, opts ...CallOption) (BatchResult, error)
+	BatchAll(identity Identity, calls []types.Call, opts ...CallOption) (BatchResult, error)
+	EstimateFee(identity Identity, call types.Call, opts ...CallOption) (*big.Int, error)
+	DryRun(identity Identity, call types.Call, opts ...CallOption) error
+}
+
+// Client has all the roles of a Substrate client
+type Client interface {
+	TwinManager
+	NodeReader
+	NodeWriter
+	ContractReader
+	ContractWriter
+	BridgeValidator
+	EventSource
+	Clock
+	AccountManager
+	Transactor
+	Close()
+}
+
+var _ Client = (*Substrate)(nil)
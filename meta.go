@@ -0,0 +1,131 @@
+package substrate
+
+import (
+	"sync"
+
+	"github.com/centrifuge/go-substrate-rpc-client/v4/types"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// metaCache keeps the metadata of each runtime version, so decoding data of
+// old blocks doesn't need to fetch the metadata for every block. It also
+// tracks the metadata of the latest runtime version.
+type metaCache struct {
+	m     sync.Mutex
+	metas map[uint32]Meta
+	// spec is the latest spec version seen
+	spec   uint32
+	latest Meta
+}
+
+func newMetaCache() *metaCache {
+	return &metaCache{metas: make(map[uint32]Meta)}
+}
+
+// at returns the metadata valid at the given block
+func (c *metaCache) at(cl Conn, block types.Hash) (Meta, error) {
+	version, err := cl.RPC.State.GetRuntimeVersion(block)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get runtime version")
+	}
+
+	return c.resolve(uint32(version.SpecVersion), false, func() (Meta, error) {
+		return cl.RPC.State.GetMetadata(block)
+	})
+}
+
+// current returns the metadata of the current runtime version
+func (c *metaCache) current(cl Conn) (Meta, error) {
+	version, err := cl.RPC.State.GetRuntimeVersionLatest()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get runtime version")
+	}
+
+	return c.upgrade(cl, uint32(version.SpecVersion))
+}
+
+// upgrade returns the metadata of the given spec version which is the
+// current runtime version. The metadata is fetched if not known yet.
+func (c *metaCache) upgrade(cl Conn, spec uint32) (Meta, error) {
+	return c.resolve(spec, true, func() (Meta, error) {
+		return cl.RPC.State.GetMetadataLatest()
+	})
+}
+
+// resolve returns the cached metadata of spec or fetches it. If current is set
+// spec is the current runtime version.
+func (c *metaCache) resolve(spec uint32, current bool, fetch func() (Meta, error)) (Meta, error) {
+	c.m.Lock()
+	meta, ok := c.metas[spec]
+	c.m.Unlock()
+
+	if !ok {
+		var err error
+		meta, err = fetch()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get metadata")
+		}
+	}
+
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	c.metas[spec] = meta
+	if current && (spec > c.spec || c.latest == nil) {
+		log.Debug().Uint32("spec", spec).Msg("using metadata of new runtime version")
+		c.spec = spec
+		c.latest = meta
+		types.SetSerDeOptions(types.SerDeOptionsFromMetadata(meta))
+	}
+
+	return meta, nil
+}
+
+// last returns the metadata of the latest runtime version
+// seen, or nil if not known
+func (c *metaCache) last() Meta {
+	if c == nil {
+		return nil
+	}
+
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	return c.latest
+}
+
+// eventsAt gets and decodes the events of the given block using
+// the metadata valid at that block
+func eventsAt(cl Conn, cache *metaCache, block types.Hash) (*EventRecords, error) {
+	meta, err := cache.at(cl, block)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := types.CreateStorageKey(meta, "System", "Events", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := cl.RPC.State.GetStorageRaw(key, block)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get block events")
+	}
+
+	return decodeEvents(meta, block, *raw)
+}
+
+// decodeEvents decodes the raw events of the given block
+func decodeEvents(meta Meta, block types.Hash, raw types.StorageDataRaw) (*EventRecords, error) {
+	events := EventRecords{}
+	if len(raw) == 0 {
+		return &events, nil
+	}
+
+	if err := decodeEventRecords(meta, raw, &events); err != nil {
+		return nil, errors.Wrapf(err, "failed to decode events of block '%s'", block.Hex())
+	}
+
+	return &events, nil
+}
@@ -0,0 +1,93 @@
+package substrate
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/centrifuge/go-substrate-rpc-client/v4/types"
+	"github.com/stretchr/testify/require"
+)
+
+// testPallet is a pallet of the metadata built by testMetadata. Calls, events
+// and errors are the ids of their enum in the lookup, 0 if the pallet has none.
+type testPallet struct {
+	name   string
+	index  uint8
+	calls  int64
+	events int64
+	errors int64
+	// spec sets the System.Version constant of the runtime if not 0
+	spec uint32
+}
+
+// testMetadata builds V14 metadata of the pallets, lookup has the types by id
+func testMetadata(t *testing.T, lookup map[int64]*types.Si1Type, pallets ...testPallet) *types.Metadata {
+	t.Helper()
+
+	meta := &types.Metadata{
+		Version:       14,
+		AsMetadataV14: types.MetadataV14{EfficientLookup: lookup},
+	}
+
+	for _, p := range pallets {
+		pallet := types.PalletMetadataV14{Name: types.Text(p.name), Index: types.U8(p.index)}
+		if p.calls != 0 {
+			pallet.HasCalls = true
+			pallet.Calls.Type = typeID(p.calls)
+		}
+
+		if p.events != 0 {
+			pallet.HasEvents = true
+			pallet.Events.Type = typeID(p.events)
+		}
+
+		if p.errors != 0 {
+			pallet.HasErrors = true
+			pallet.Errors.Type = typeID(p.errors)
+		}
+
+		if p.spec != 0 {
+			version, err := types.EncodeToBytes(runtimeVersion{SpecName: "substrate-threefold", SpecVersion: types.U32(p.spec)})
+			require.NoError(t, err)
+			pallet.Constants = []types.ConstantMetadataV14{{Name: "Version", Value: version}}
+		}
+
+		meta.AsMetadataV14.Pallets = append(meta.AsMetadataV14.Pallets, pallet)
+	}
+
+	return meta
+}
+
+func typeID(id int64) types.Si1LookupTypeID {
+	return types.NewSi1LookupTypeID(big.NewInt(id))
+}
+
+// typeFields returns unnamed fields of the given type ids
+func typeFields(ids ...int64) []types.Si1Field {
+	var fields []types.Si1Field
+	for _, id := range ids {
+		fields = append(fields, types.Si1Field{Type: typeID(id)})
+	}
+
+	return fields
+}
+
+func primitiveType(p types.Si0TypeDefPrimitive) *types.Si1Type {
+	return &types.Si1Type{Def: types.Si1TypeDef{IsPrimitive: true, Primitive: types.Si1TypeDefPrimitive{Si0TypeDefPrimitive: p}}}
+}
+
+func compositeType(ids ...int64) *types.Si1Type {
+	return &types.Si1Type{Def: types.Si1TypeDef{IsComposite: true, Composite: types.Si1TypeDefComposite{Fields: typeFields(ids...)}}}
+}
+
+func variantType(variants ...types.Si1Variant) *types.Si1Type {
+	return &types.Si1Type{Def: types.Si1TypeDef{IsVariant: true, Variant: types.Si1TypeDefVariant{Variants: variants}}}
+}
+
+// optionType is an Option of the type with given id
+func optionType(id int64) *types.Si1Type {
+	return variantType(
+		types.Si1Variant{Name: "None", Index: 0},
+		types.Si1Variant{Name: "Some", Index: 1, Fields: typeFields(id)},
+	)
+}
@@ -0,0 +1,194 @@
+package substrate
+
+import (
+	"context"
+	"time"
+
+	"github.com/centrifuge/go-substrate-rpc-client/v4/client"
+	gethrpc "github.com/centrifuge/go-substrate-rpc-client/v4/gethrpc"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	metricsNamespace = "substrate_client"
+
+	// extrinsic outcomes
+	outcomeInBlock         = "in_block"
+	outcomeFinalized       = "finalized"
+	outcomeDropped         = "dropped"
+	outcomeInvalid         = "invalid"
+	outcomeUsurped         = "usurped"
+	outcomeTimeout         = "timeout"
+	outcomeFinalityTimeout = "finality_timeout"
+	outcomeError           = "error"
+
+	// endpoint check results
+	endpointConnected   = "connected"
+	endpointUnreachable = "unreachable"
+	endpointUnhealthy   = "unhealthy"
+	endpointBehind      = "behind"
+
+	// pool eviction reasons
+	evictionExpired   = "expired"
+	evictionUnhealthy = "unhealthy"
+	evictionFull      = "full"
+)
+
+// Metrics instruments the RPC calls, extrinsics and connection pool of a
+// manager. Create it with NewMetrics and pass it with WithMetrics. A nil
+// Metrics records nothing.
+type Metrics struct {
+	rpc          *prometheus.HistogramVec
+	extrinsics   *prometheus.HistogramVec
+	endpoints    *prometheus.CounterVec
+	idle         *prometheus.GaugeVec
+	poolRequests *prometheus.CounterVec
+	evictions    *prometheus.CounterVec
+}
+
+// NewMetrics creates the metrics and registers them on reg
+func NewMetrics(reg prometheus.Registerer) (*Metrics, error) {
+	m := &Metrics{
+		rpc: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "rpc_duration_seconds",
+			Help:      "Duration of RPC calls by method and status (ok or error).",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "status"}),
+		extrinsics: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "extrinsic_duration_seconds",
+			Help:      "Duration of submitted extrinsics by outcome, from signing to the last status.",
+			Buckets:   []float64{1, 2.5, 5, 7.5, 10, 15, 20, 30, 45, 60, 120},
+		}, []string{"outcome"}),
+		endpoints: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "endpoint_checks_total",
+			Help:      "Endpoint health checks by url and result.",
+		}, []string{"url", "result"}),
+		idle: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "pool_idle_connections",
+			Help:      "Idle connections in the pool by url.",
+		}, []string{"url"}),
+		poolRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "pool_requests_total",
+			Help:      "Connection requests by result, hit if an idle connection was reused.",
+		}, []string{"result"}),
+		evictions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "pool_evictions_total",
+			Help:      "Connections closed by the pool by url and reason.",
+		}, []string{"url", "reason"}),
+	}
+
+	for _, c := range []prometheus.Collector{m.rpc, m.extrinsics, m.endpoints, m.idle, m.poolRequests, m.evictions} {
+		if err := reg.Register(c); err != nil {
+			return nil, errors.Wrap(err, "failed to register metrics")
+		}
+	}
+
+	return m, nil
+}
+
+func (m *Metrics) observeRPC(method string, start time.Time, err error) {
+	if m == nil {
+		return
+	}
+
+	status := "ok"
+	if err != nil {
+		status = "error"
+	}
+
+	m.rpc.WithLabelValues(method, status).Observe(time.Since(start).Seconds())
+}
+
+func (m *Metrics) observeExtrinsic(outcome string, start time.Time) {
+	if m == nil {
+		return
+	}
+
+	m.extrinsics.WithLabelValues(outcome).Observe(time.Since(start).Seconds())
+}
+
+func (m *Metrics) endpoint(url, result string) {
+	if m == nil {
+		return
+	}
+
+	m.endpoints.WithLabelValues(url, result).Inc()
+}
+
+func (m *Metrics) setIdle(url string, count int) {
+	if m == nil {
+		return
+	}
+
+	m.idle.WithLabelValues(url).Set(float64(count))
+}
+
+func (m *Metrics) poolRequest(hit bool) {
+	if m == nil {
+		return
+	}
+
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+
+	m.poolRequests.WithLabelValues(result).Inc()
+}
+
+func (m *Metrics) evict(url, reason string) {
+	if m == nil {
+		return
+	}
+
+	m.evictions.WithLabelValues(url, reason).Inc()
+}
+
+// client wraps cl so the duration of its calls is recorded
+func (m *Metrics) client(cl client.Client) client.Client {
+	if m == nil {
+		return cl
+	}
+
+	return &metricsClient{Client: cl, m: m}
+}
+
+// metricsClient records the duration of the calls made over the client
+type metricsClient struct {
+	client.Client
+	m *Metrics
+}
+
+// Call implements client.Client
+func (c *metricsClient) Call(result interface{}, method string, args ...interface{}) error {
+	start := time.Now()
+	err := c.Client.Call(result, method, args...)
+	c.m.observeRPC(method, start, err)
+	return err
+}
+
+// CallContext keeps the calls bound to a context cancellable when wrapped
+// with withContext
+func (c *metricsClient) CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	start := time.Now()
+	err := (&ctxClient{Client: c.Client, ctx: ctx}).Call(result, method, args...)
+	c.m.observeRPC(method, start, err)
+	return err
+}
+
+// Subscribe implements client.Client, the recorded duration is the time
+// taken to set up the subscription
+func (c *metricsClient) Subscribe(ctx context.Context, namespace, subscribeMethodSuffix, unsubscribeMethodSuffix,
+	notificationMethodSuffix string, channel interface{}, args ...interface{}) (*gethrpc.ClientSubscription, error) {
+	start := time.Now()
+	sub, err := c.Client.Subscribe(ctx, namespace, subscribeMethodSuffix, unsubscribeMethodSuffix, notificationMethodSuffix, channel, args...)
+	c.m.observeRPC(namespace+"_"+subscribeMethodSuffix, start, err)
+	return sub, err
+}
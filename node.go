@@ -112,6 +112,86 @@ func (m *OptionPublicConfig) Decode(decoder scale.Decoder) (err error) {
 	}
 }
 
+// Power is the power target of a node
+type Power struct {
+	IsUp   bool
+	IsDown bool
+}
+
+// Decode implementation for the enum type
+func (r *Power) Decode(decoder scale.Decoder) error {
+	b, err := decoder.ReadOneByte()
+	if err != nil {
+		return err
+	}
+
+	switch b {
+	case 0:
+		r.IsUp = true
+	case 1:
+		r.IsDown = true
+	default:
+		return fmt.Errorf("unknown Power value")
+	}
+
+	return nil
+}
+
+// Encode implementation
+func (r Power) Encode(encoder scale.Encoder) (err error) {
+	if r.IsUp {
+		err = encoder.PushByte(0)
+	} else if r.IsDown {
+		err = encoder.PushByte(1)
+	}
+
+	return
+}
+
+// PowerState is the power state of a node, if down it holds
+// the block number at which the node went down
+type PowerState struct {
+	IsUp   bool
+	IsDown bool
+	AsDown types.U32
+}
+
+// Decode implementation for the enum type
+func (r *PowerState) Decode(decoder scale.Decoder) error {
+	b, err := decoder.ReadOneByte()
+	if err != nil {
+		return err
+	}
+
+	switch b {
+	case 0:
+		r.IsUp = true
+	case 1:
+		r.IsDown = true
+		if err := decoder.Decode(&r.AsDown); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown PowerState value")
+	}
+
+	return nil
+}
+
+// Encode implementation
+func (r PowerState) Encode(encoder scale.Encoder) (err error) {
+	if r.IsUp {
+		err = encoder.PushByte(0)
+	} else if r.IsDown {
+		if err = encoder.PushByte(1); err != nil {
+			return err
+		}
+		err = encoder.Encode(r.AsDown)
+	}
+
+	return
+}
+
 type Interface struct {
 	Name string
 	Mac  string
@@ -159,7 +239,7 @@ type NodeExtra struct {
 	SerialNumber string
 }
 
-//GetNodeByTwinID gets a node by twin id
+// GetNodeByTwinID gets a node by twin id
 func (s *Substrate) GetNodeByTwinID(twin uint32) (uint32, error) {
 	cl, meta, err := s.getClient()
 	if err != nil {
@@ -212,7 +292,7 @@ type ScannedNode struct {
 }
 
 func (s *Substrate) ScanNodes(ctx context.Context, from, to uint32) (<-chan ScannedNode, error) {
-	cl, meta, err := s.getClient()
+	cl, meta, err := s.WithContext(ctx).getClient()
 	if err != nil {
 		return nil, err
 	}
@@ -322,10 +402,15 @@ func (s *Substrate) CreateNode(identity Identity, node Node) (uint32, error) {
 		return 0, errors.Wrap(err, "failed to create call")
 	}
 
-	if _, err := s.Call(cl, meta, identity, c); err != nil {
+	receipt, err := s.Submit(cl, meta, identity, c)
+	if err != nil {
 		return 0, errors.Wrap(err, "failed to create node")
 	}
 
+	if events := receipt.Events.TfgridModule_NodeStored; len(events) > 0 {
+		return uint32(events[0].Node.ID), nil
+	}
+
 	return s.GetNodeByTwinID(uint32(node.TwinID))
 
 }
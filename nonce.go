@@ -0,0 +1,128 @@
+package substrate
+
+import (
+	"sync"
+
+	"github.com/centrifuge/go-substrate-rpc-client/v4/types"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// nonceTracker hands out sequential nonces per account, so multiple
+// extrinsics can be submitted concurrently with the same identity
+// without waiting for each other to be included in a block.
+type nonceTracker struct {
+	m        sync.Mutex
+	accounts map[types.AccountID]*accountNonce
+}
+
+type accountNonce struct {
+	m sync.Mutex
+	// next is the next nonce to hand out, 0 means
+	// the nonce need to be synced from the chain
+	next uint64
+	// inflight is the number of extrinsics submitted
+	// with an acquired nonce that are not done yet
+	inflight int
+}
+
+func newNonceTracker() *nonceTracker {
+	return &nonceTracker{
+		accounts: make(map[types.AccountID]*accountNonce),
+	}
+}
+
+func (n *nonceTracker) account(id types.AccountID) *accountNonce {
+	n.m.Lock()
+	defer n.m.Unlock()
+
+	account, ok := n.accounts[id]
+	if !ok {
+		account = &accountNonce{}
+		n.accounts[id] = account
+	}
+
+	return account
+}
+
+// acquire returns the nonce to use for the next extrinsic of the identity.
+// The chain next index (which accounts for transactions pending in the pool)
+// is compared to the locally tracked nonce and the highest is used, so
+// nonces handed out but not yet submitted are not reused. If no extrinsic
+// of the identity is in flight the chain index is used as is, a nonce that
+// was handed out but never made it to the chain (like a transaction dropped
+// from the pool) would otherwise make all the next extrinsics Future.
+// Every acquire must be followed by a release once the extrinsic is done.
+func (n *nonceTracker) acquire(s *Substrate, cl Conn, meta Meta, identity Identity) (uint64, error) {
+	account := n.account(types.NewAccountID(identity.PublicKey()))
+
+	account.m.Lock()
+	defer account.m.Unlock()
+
+	remote, err := s.nextIndex(cl, meta, identity)
+	if err != nil {
+		return 0, err
+	}
+
+	if account.inflight == 0 && account.next > remote {
+		log.Debug().Uint64("local", account.next).Uint64("chain", remote).Msg("nonce is ahead of the chain, syncing")
+		account.next = remote
+	}
+
+	nonce := account.next
+	if remote > nonce {
+		nonce = remote
+	}
+
+	account.next = nonce + 1
+	account.inflight++
+	return nonce, nil
+}
+
+// release marks an extrinsic with an acquired nonce of the identity as done
+func (n *nonceTracker) release(identity Identity) {
+	account := n.account(types.NewAccountID(identity.PublicKey()))
+
+	account.m.Lock()
+	defer account.m.Unlock()
+
+	account.inflight--
+}
+
+// resync drops the locally tracked nonce of the identity, the next acquire
+// uses the chain next index. It's called when an extrinsic with the given
+// nonce provably didn't make it to the chain so the nonce can be reused, or
+// when it's stuck in the pool waiting for a nonce that never made it.
+func (n *nonceTracker) resync(identity Identity, nonce uint64) {
+	account := n.account(types.NewAccountID(identity.PublicKey()))
+
+	account.m.Lock()
+	defer account.m.Unlock()
+
+	if account.next > nonce {
+		account.next = 0
+	}
+}
+
+// nextIndex gets the next nonce of the account from the chain, this includes
+// transactions that are still in the pool. It falls back to the account nonce
+// if the node doesn't support the call.
+func (s *Substrate) nextIndex(cl Conn, meta Meta, identity Identity) (uint64, error) {
+	var index uint64
+	err := cl.Client.Call(&index, "system_accountNextIndex", identity.Address())
+	if err == nil {
+		return index, nil
+	}
+
+	if err := s.Context().Err(); err != nil {
+		return 0, err
+	}
+
+	log.Debug().Err(err).Msg("failed to get account next index, using account nonce")
+	account, err := s.getAccount(cl, meta, identity)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to get account")
+	}
+
+	return uint64(account.Nonce), nil
+}
@@ -0,0 +1,124 @@
+package substrate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+
+	gethrpc "github.com/centrifuge/go-substrate-rpc-client/v4/gethrpc"
+	"github.com/stretchr/testify/require"
+)
+
+// indexClient answers system_accountNextIndex with a fixed index
+type indexClient struct {
+	index uint64
+}
+
+func (c *indexClient) Call(result interface{}, method string, args ...interface{}) error {
+	if method != "system_accountNextIndex" {
+		return fmt.Errorf("unexpected call to %s", method)
+	}
+
+	data, err := json.Marshal(c.index)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, result)
+}
+
+func (c *indexClient) Subscribe(ctx context.Context, namespace, subscribeMethodSuffix, unsubscribeMethodSuffix,
+	notificationMethodSuffix string, channel interface{}, args ...interface{}) (*gethrpc.ClientSubscription, error) {
+	return nil, fmt.Errorf("not supported")
+}
+
+func (c *indexClient) URL() string {
+	return "test"
+}
+
+func (c *indexClient) Close() {}
+
+func TestNonceTracker(t *testing.T) {
+	require := require.New(t)
+
+	identity, err := NewIdentityFromSr25519Phrase("//Alice")
+	require.NoError(err)
+
+	remote := &indexClient{index: 10}
+	sub := &Substrate{cl: newConn(remote), nonces: newNonceTracker()}
+	cl, meta, err := sub.getClient()
+	require.NoError(err)
+
+	const count = 20
+	var (
+		wg     sync.WaitGroup
+		m      sync.Mutex
+		nonces = make(map[uint64]struct{})
+	)
+
+	for i := 0; i < count; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			nonce, err := sub.nonces.acquire(sub, cl, meta, identity)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			m.Lock()
+			defer m.Unlock()
+			nonces[nonce] = struct{}{}
+		}()
+	}
+	wg.Wait()
+
+	require.Len(nonces, count)
+	for i := uint64(10); i < 10+count; i++ {
+		require.Contains(nonces, i)
+	}
+
+	// the chain caught up with some of the transactions, but one
+	// of them was dropped, so we start again from the chain index
+	remote.index = 15
+	sub.nonces.resync(identity, 15)
+
+	nonce, err := sub.nonces.acquire(sub, cl, meta, identity)
+	require.NoError(err)
+	require.EqualValues(15, nonce)
+}
+
+func TestNonceTrackerIdle(t *testing.T) {
+	require := require.New(t)
+
+	identity, err := NewIdentityFromSr25519Phrase("//Alice")
+	require.NoError(err)
+
+	remote := &indexClient{index: 10}
+	sub := &Substrate{cl: newConn(remote), nonces: newNonceTracker()}
+	cl, meta, err := sub.getClient()
+	require.NoError(err)
+
+	for i := uint64(10); i < 13; i++ {
+		nonce, err := sub.nonces.acquire(sub, cl, meta, identity)
+		require.NoError(err)
+		require.Equal(i, nonce)
+	}
+
+	// the chain is behind while extrinsics are in flight
+	sub.nonces.release(identity)
+	sub.nonces.release(identity)
+	nonce, err := sub.nonces.acquire(sub, cl, meta, identity)
+	require.NoError(err)
+	require.EqualValues(13, nonce)
+
+	// nothing is in flight, the extrinsics after 10 never made it
+	sub.nonces.release(identity)
+	sub.nonces.release(identity)
+	remote.index = 11
+	nonce, err = sub.nonces.acquire(sub, cl, meta, identity)
+	require.NoError(err)
+	require.EqualValues(11, nonce)
+}
@@ -0,0 +1,285 @@
+package substrate
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/centrifuge/go-substrate-rpc-client/v4/types"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	defaultChunkSize = 100
+)
+
+// CheckpointStore persists the last block processed by a Processor
+type CheckpointStore interface {
+	// Get returns the last processed block, ok is false if no block
+	// was processed yet
+	Get() (block uint32, ok bool, err error)
+	// Set sets the last processed block
+	Set(block uint32) error
+}
+
+// FileCheckpointStore is a CheckpointStore that keeps the checkpoint in a file
+type FileCheckpointStore struct {
+	path string
+}
+
+var _ CheckpointStore = (*FileCheckpointStore)(nil)
+
+// NewFileCheckpointStore creates a checkpoint store backed by the file at path
+func NewFileCheckpointStore(path string) *FileCheckpointStore {
+	return &FileCheckpointStore{path: path}
+}
+
+// Get implements CheckpointStore
+func (f *FileCheckpointStore) Get() (uint32, bool, error) {
+	data, err := ioutil.ReadFile(f.path)
+	if os.IsNotExist(err) {
+		return 0, false, nil
+	} else if err != nil {
+		return 0, false, errors.Wrap(err, "failed to read checkpoint")
+	}
+
+	block, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 32)
+	if err != nil {
+		return 0, false, errors.Wrap(err, "invalid checkpoint")
+	}
+
+	return uint32(block), true, nil
+}
+
+// Set implements CheckpointStore. The file is replaced atomically so a
+// crash never leaves a partial checkpoint behind.
+func (f *FileCheckpointStore) Set(block uint32) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(f.path), filepath.Base(f.path))
+	if err != nil {
+		return errors.Wrap(err, "failed to create checkpoint file")
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := fmt.Fprintf(tmp, "%d\n", block); err != nil {
+		tmp.Close()
+		return errors.Wrap(err, "failed to write checkpoint")
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return errors.Wrap(err, "failed to sync checkpoint")
+	}
+
+	if err := tmp.Close(); err != nil {
+		return errors.Wrap(err, "failed to close checkpoint")
+	}
+
+	return os.Rename(tmp.Name(), f.path)
+}
+
+// Handler processes the events of a single block
+type Handler func(events BlockEvents) error
+
+// ProcessorOption configures a processor
+type ProcessorOption func(*Processor)
+
+// WithStartHeight sets the block to start processing from if there is no
+// checkpoint yet. Defaults to block 1.
+func WithStartHeight(height uint32) ProcessorOption {
+	return func(p *Processor) {
+		p.start = height
+	}
+}
+
+// WithChunkSize sets how many blocks are fetched at once while catching up
+func WithChunkSize(size uint32) ProcessorOption {
+	return func(p *Processor) {
+		p.chunk = size
+	}
+}
+
+// WithBestBlocks processes blocks as soon as they are imported instead of
+// waiting for them to be finalized. Blocks are then processed exactly once
+// but a reorg can retract a block that was already processed and
+// checkpointed, the handler must cope with that.
+func WithBestBlocks() ProcessorOption {
+	return func(p *Processor) {
+		p.finalized = false
+	}
+}
+
+// Processor calls a handler for the events of each finalized block exactly
+// once (given the handler and the store are reliable) in order. It catches
+// up from the checkpoint in chunks, then follows new blocks as they come.
+type Processor struct {
+	mgr       Manager
+	store     CheckpointStore
+	handler   Handler
+	start     uint32
+	chunk     uint32
+	finalized bool
+}
+
+// NewProcessor creates a new processor
+func NewProcessor(mgr Manager, store CheckpointStore, handler Handler, opts ...ProcessorOption) *Processor {
+	p := &Processor{
+		mgr:       mgr,
+		store:     store,
+		handler:   handler,
+		start:     1,
+		chunk:     defaultChunkSize,
+		finalized: true,
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	if p.chunk == 0 {
+		p.chunk = 1
+	}
+
+	return p
+}
+
+// Run processes blocks until ctx is done or the handler fails. The checkpoint
+// is only updated after the handler succeeds, so after a failure Run can be
+// called again to continue with the failed block.
+func (p *Processor) Run(ctx context.Context) error {
+	next, err := p.next()
+	if err != nil {
+		return err
+	}
+
+	next, err = p.catchUp(ctx, next)
+	if err != nil {
+		return err
+	}
+
+	log.Info().Uint32("block", next).Msg("processor caught up, following new blocks")
+
+	// stops the subscription if the handler fails
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	s, err := p.mgr.SubstrateContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	ch, err := s.SubscribeEvents(ctx, next, p.finalized)
+	s.Close()
+	if err != nil {
+		return errors.Wrap(err, "failed to subscribe to events")
+	}
+
+	for block := range ch {
+		if err := p.process(block); err != nil {
+			return err
+		}
+	}
+
+	return ctx.Err()
+}
+
+// next gets the next block to process
+func (p *Processor) next() (uint32, error) {
+	block, ok, err := p.store.Get()
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to get checkpoint")
+	}
+
+	if !ok {
+		return p.start, nil
+	}
+
+	return block + 1, nil
+}
+
+// catchUp processes history blocks in chunks until the head
+// is reached, and returns the next block to process
+func (p *Processor) catchUp(ctx context.Context, next uint32) (uint32, error) {
+	s, err := p.mgr.SubstrateContext(ctx)
+	if err != nil {
+		return next, err
+	}
+	defer s.Close()
+
+	cl, _, err := s.getClient()
+	if err != nil {
+		return next, err
+	}
+
+	cache := s.metas
+	for {
+		head, err := p.head(cl)
+		if err != nil {
+			return next, err
+		}
+
+		if next > head {
+			return next, nil
+		}
+
+		to := next + p.chunk - 1
+		if to > head {
+			to = head
+		}
+
+		blocks, err := blockEventsRange(cl, cache, next, to)
+		if err != nil {
+			return next, err
+		}
+
+		for _, block := range blocks {
+			if err := p.process(block); err != nil {
+				return next, err
+			}
+			next = block.Number + 1
+		}
+
+		if err := ctx.Err(); err != nil {
+			return next, err
+		}
+	}
+}
+
+// head returns the number of the chain head, or of the
+// finalized head if only finalized blocks are processed
+func (p *Processor) head(cl Conn) (uint32, error) {
+	var header *types.Header
+	var err error
+	if p.finalized {
+		var hash types.Hash
+		hash, err = cl.RPC.Chain.GetFinalizedHead()
+		if err != nil {
+			return 0, errors.Wrap(err, "failed to get finalized head")
+		}
+		header, err = cl.RPC.Chain.GetHeader(hash)
+	} else {
+		header, err = cl.RPC.Chain.GetHeaderLatest()
+	}
+
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to get chain head")
+	}
+
+	return uint32(header.Number), nil
+}
+
+func (p *Processor) process(block BlockEvents) error {
+	if err := p.handler(block); err != nil {
+		return errors.Wrapf(err, "failed to process block '%d'", block.Number)
+	}
+
+	if err := p.store.Set(block.Number); err != nil {
+		return errors.Wrapf(err, "failed to set checkpoint to block '%d'", block.Number)
+	}
+
+	return nil
+}
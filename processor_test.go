@@ -0,0 +1,25 @@
+package substrate
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileCheckpointStore(t *testing.T) {
+	require := require.New(t)
+
+	store := NewFileCheckpointStore(filepath.Join(t.TempDir(), "checkpoint"))
+	_, ok, err := store.Get()
+	require.NoError(err)
+	require.False(ok)
+
+	require.NoError(store.Set(100))
+	require.NoError(store.Set(101))
+
+	block, ok, err := store.Get()
+	require.NoError(err)
+	require.True(ok)
+	require.EqualValues(101, block)
+}
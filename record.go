@@ -0,0 +1,384 @@
+package substrate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// message is a JSON-RPC request, response or notification
+type message struct {
+	Version string           `json:"jsonrpc,omitempty"`
+	ID      json.RawMessage  `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+	Result  json.RawMessage  `json:"result,omitempty"`
+	Error   *json.RawMessage `json:"error,omitempty"`
+}
+
+// notificationParams are the params of a subscription notification
+type notificationParams struct {
+	Subscription string          `json:"subscription"`
+	Result       json.RawMessage `json:"result"`
+}
+
+// RecordedCall is a recorded request and the response of the node. For
+// subscriptions the result is the subscription id and the notifications
+// are the results of the notifications sent for it.
+type RecordedCall struct {
+	Method        string            `json:"method"`
+	Params        json.RawMessage   `json:"params,omitempty"`
+	Result        json.RawMessage   `json:"result,omitempty"`
+	Error         *json.RawMessage  `json:"error,omitempty"`
+	Notification  string            `json:"notification,omitempty"`
+	Notifications []json.RawMessage `json:"notifications,omitempty"`
+}
+
+// isSubscription checks if the method starts a subscription
+func isSubscription(method string) bool {
+	return strings.Contains(method, "_subscribe") || strings.HasSuffix(method, "AndWatchExtrinsic")
+}
+
+// isUnsubscription checks if the method cancels a subscription
+func isUnsubscription(method string) bool {
+	return strings.Contains(method, "_unsubscribe")
+}
+
+// Recorder records all calls made over the transports it dials, so they
+// can be replayed later with a Replayer
+//
+//	rec := NewRecorder(DialWebsocket)
+//	mgr := NewManagerWithOptions(urls, WithDialer(rec.Dial))
+//	// use mgr then save the session
+//	err := rec.Save("testdata/session.json")
+type Recorder struct {
+	dialer Dialer
+
+	m     sync.Mutex
+	calls []*RecordedCall
+}
+
+// NewRecorder creates a recorder of the connections opened with dialer
+func NewRecorder(dialer Dialer) *Recorder {
+	return &Recorder{dialer: dialer}
+}
+
+// Dial implements Dialer
+func (r *Recorder) Dial(ctx context.Context, endpoint string) (Transport, error) {
+	t, err := r.dialer(ctx, endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	return &recordTransport{
+		Transport: t,
+		r:         r,
+		requests:  make(map[string]*RecordedCall),
+		subs:      make(map[string]*RecordedCall),
+	}, nil
+}
+
+// Calls returns the calls recorded so far
+func (r *Recorder) Calls() []RecordedCall {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	calls := make([]RecordedCall, 0, len(r.calls))
+	for _, call := range r.calls {
+		calls = append(calls, *call)
+	}
+
+	return calls
+}
+
+// Save writes the recorded calls to a fixture file
+func (r *Recorder) Save(path string) error {
+	data, err := json.MarshalIndent(r.Calls(), "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to encode recorded calls")
+	}
+
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+// recordTransport records the messages of a single connection, request
+// and subscription ids are only unique per connection
+type recordTransport struct {
+	Transport
+	r *Recorder
+
+	m        sync.Mutex
+	requests map[string]*RecordedCall
+	subs     map[string]*RecordedCall
+}
+
+func (t *recordTransport) Send(msg json.RawMessage) error {
+	var req message
+	// batches are sent as they are without being recorded
+	if err := json.Unmarshal(msg, &req); err == nil && req.Method != "" {
+		call := &RecordedCall{Method: req.Method, Params: req.Params}
+
+		t.r.m.Lock()
+		t.r.calls = append(t.r.calls, call)
+		t.r.m.Unlock()
+
+		t.m.Lock()
+		t.requests[string(req.ID)] = call
+		t.m.Unlock()
+	}
+
+	return t.Transport.Send(msg)
+}
+
+func (t *recordTransport) Receive() (json.RawMessage, error) {
+	msg, err := t.Transport.Receive()
+	if err != nil {
+		return msg, err
+	}
+
+	var resp message
+	if err := json.Unmarshal(msg, &resp); err != nil {
+		return msg, nil
+	}
+
+	t.r.m.Lock()
+	defer t.r.m.Unlock()
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	if len(resp.ID) != 0 {
+		call, ok := t.requests[string(resp.ID)]
+		if !ok {
+			return msg, nil
+		}
+		delete(t.requests, string(resp.ID))
+
+		call.Result = resp.Result
+		call.Error = resp.Error
+
+		var id string
+		if isSubscription(call.Method) && json.Unmarshal(resp.Result, &id) == nil {
+			t.subs[id] = call
+		}
+	} else if len(resp.Params) != 0 {
+		var params notificationParams
+		if err := json.Unmarshal(resp.Params, &params); err != nil {
+			return msg, nil
+		}
+
+		if call, ok := t.subs[params.Subscription]; ok {
+			call.Notification = resp.Method
+			call.Notifications = append(call.Notifications, params.Result)
+		}
+	}
+
+	return msg, nil
+}
+
+// Replayer answers calls with the responses of a recorded session. Calls
+// are matched by method and params in the order they were recorded. Once
+// all matching calls are used the last one is repeated. Calls that have
+// no exact match, like submitting extrinsics with new signatures, are
+// answered with the next recorded call of the same method. Note that sr25519
+// signatures are randomized, so extrinsics signed with sr25519 keys can't be
+// found in the replayed blocks, record sessions with ed25519 keys instead.
+//
+//	rep, err := LoadReplayer("testdata/session.json")
+//	mgr := NewManagerWithOptions([]string{"ws://replay"}, WithDialer(rep.Dial), WithAcceptableDelay(0))
+//
+// Notifications of a subscription are sent right after it's started.
+type Replayer struct {
+	m     sync.Mutex
+	calls []RecordedCall
+	used  []bool
+	// last is the last used call by key
+	last map[string]int
+}
+
+// NewReplayer creates a replayer of the given calls
+func NewReplayer(calls []RecordedCall) *Replayer {
+	return &Replayer{
+		calls: calls,
+		used:  make([]bool, len(calls)),
+		last:  make(map[string]int),
+	}
+}
+
+// LoadReplayer creates a replayer of the calls saved in a fixture file
+func LoadReplayer(path string) (*Replayer, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read recorded calls")
+	}
+
+	var calls []RecordedCall
+	if err := json.Unmarshal(data, &calls); err != nil {
+		return nil, errors.Wrap(err, "failed to decode recorded calls")
+	}
+
+	return NewReplayer(calls), nil
+}
+
+// callKey identifies calls with the same method and params
+func callKey(method string, params json.RawMessage) string {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, params); err != nil {
+		buf.Reset()
+		buf.Write(params)
+	}
+
+	return method + buf.String()
+}
+
+// match returns the recorded call for the request
+func (r *Replayer) match(method string, params json.RawMessage) (RecordedCall, bool) {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	key := callKey(method, params)
+	next := func(exact bool) (RecordedCall, bool) {
+		for i, call := range r.calls {
+			if r.used[i] || call.Method != method {
+				continue
+			}
+
+			if exact && callKey(call.Method, call.Params) != key {
+				continue
+			}
+
+			r.used[i] = true
+			r.last[key] = i
+			return call, true
+		}
+
+		return RecordedCall{}, false
+	}
+
+	if call, ok := next(true); ok {
+		return call, true
+	}
+
+	if i, ok := r.last[key]; ok {
+		return r.calls[i], true
+	}
+
+	return next(false)
+}
+
+// Dial implements Dialer
+func (r *Replayer) Dial(ctx context.Context, endpoint string) (Transport, error) {
+	return &replayTransport{
+		r:    r,
+		out:  make(chan json.RawMessage, 1024),
+		done: make(chan struct{}),
+	}, nil
+}
+
+// replayTransport answers the requests sent over it from the replayer
+type replayTransport struct {
+	r    *Replayer
+	out  chan json.RawMessage
+	done chan struct{}
+
+	m      sync.Mutex
+	nextID int
+	closed bool
+}
+
+func (t *replayTransport) send(msg interface{}) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	select {
+	case t.out <- data:
+		return nil
+	case <-t.done:
+		return fmt.Errorf("transport closed")
+	}
+}
+
+func (t *replayTransport) Send(msg json.RawMessage) error {
+	var req message
+	if err := json.Unmarshal(msg, &req); err != nil {
+		return errors.Wrap(err, "invalid request")
+	}
+
+	resp := message{Version: "2.0", ID: req.ID}
+	if isUnsubscription(req.Method) {
+		// subscription ids are not the recorded ones
+		resp.Result = json.RawMessage("true")
+		return t.send(resp)
+	}
+
+	call, ok := t.r.match(req.Method, req.Params)
+	if !ok {
+		data := json.RawMessage(fmt.Sprintf(`{"code":-32601,"message":"no recorded call for method '%s'"}`, req.Method))
+		resp.Error = &data
+		return t.send(resp)
+	}
+
+	if call.Error != nil || !isSubscription(req.Method) {
+		resp.Result, resp.Error = call.Result, call.Error
+		return t.send(resp)
+	}
+
+	// subscriptions get a new id so they don't clash with other
+	// subscriptions replayed over the same connection
+	t.m.Lock()
+	t.nextID++
+	id := strconv.Itoa(t.nextID)
+	t.m.Unlock()
+
+	resp.Result = json.RawMessage(strconv.Quote(id))
+	if err := t.send(resp); err != nil {
+		return err
+	}
+
+	for _, result := range call.Notifications {
+		notification := struct {
+			Version string             `json:"jsonrpc"`
+			Method  string             `json:"method"`
+			Params  notificationParams `json:"params"`
+		}{
+			Version: "2.0",
+			Method:  call.Notification,
+			Params:  notificationParams{Subscription: id, Result: result},
+		}
+
+		if err := t.send(notification); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (t *replayTransport) Receive() (json.RawMessage, error) {
+	select {
+	case msg := <-t.out:
+		return msg, nil
+	case <-t.done:
+		return nil, fmt.Errorf("transport closed")
+	}
+}
+
+func (t *replayTransport) Close() error {
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	if !t.closed {
+		t.closed = true
+		close(t.done)
+	}
+
+	return nil
+}
@@ -0,0 +1,249 @@
+package substrate
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/quick"
+
+	"github.com/centrifuge/go-substrate-rpc-client/v4/types"
+	"github.com/stretchr/testify/require"
+)
+
+// roundTrip checks that value encodes to data and that data decodes to value
+func roundTrip(t *testing.T, value interface{}, data []byte, msg ...interface{}) {
+	encoded, err := types.EncodeToBytes(value)
+	require.NoError(t, err, msg...)
+	require.Equal(t, data, encoded, msg...)
+
+	decoded := reflect.New(reflect.TypeOf(value))
+	require.NoError(t, types.DecodeFromBytes(data, decoded.Interface()), msg...)
+	require.Equal(t, value, decoded.Elem().Interface(), msg...)
+}
+
+func TestEnumCodec(t *testing.T) {
+	// every variant of the enums with a custom codec, the bytes follow
+	// the variant order of the runtime types
+	cases := []struct {
+		value interface{}
+		data  []byte
+	}{
+		{DeletedState{IsCanceledByUser: true}, []byte{0}},
+		{DeletedState{IsOutOfFunds: true}, []byte{1}},
+		{DeletedState{IsCanceledByCollective: true}, []byte{2}},
+		{ContractState{IsCreated: true}, []byte{0}},
+		{ContractState{IsDeleted: true, AsDeleted: DeletedState{IsOutOfFunds: true}}, []byte{1, 1}},
+		{ContractState{IsGracePeriod: true, AsGracePeriodBlockNumber: 258}, []byte{2, 2, 1, 0, 0, 0, 0, 0, 0}},
+		{ContractType{IsNodeContract: true, NodeContract: NodeContract{
+			Node:           1,
+			DeploymentData: []byte{0xff},
+			DeploymentHash: "h",
+			PublicIPsCount: 1,
+			PublicIPs:      []PublicIP{{IP: "i", Gateway: "g", ContractID: 2}},
+		}}, []byte{0, 1, 0, 0, 0, 4, 0xff, 4, 'h', 1, 0, 0, 0, 4, 4, 'i', 4, 'g', 2, 0, 0, 0, 0, 0, 0, 0}},
+		{ContractType{IsNameContract: true, NameContract: NameContract{Name: "n"}}, []byte{1, 4, 'n'}},
+		{ContractType{IsRentContract: true, RentContract: RentContract{Node: 3}}, []byte{2, 3, 0, 0, 0}},
+		{DiscountLevel{IsNone: true}, []byte{0}},
+		{DiscountLevel{IsDefault: true}, []byte{1}},
+		{DiscountLevel{IsBronze: true}, []byte{2}},
+		{DiscountLevel{IsSilver: true}, []byte{3}},
+		{DiscountLevel{IsGold: true}, []byte{4}},
+		{ServiceContractState{IsCreated: true}, []byte{0}},
+		{ServiceContractState{IsAgreementReady: true}, []byte{1}},
+		{ServiceContractState{IsApprovedByBoth: true}, []byte{2}},
+		{Cause{IsCanceledByUser: true}, []byte{0}},
+		{Cause{IsOutOfFunds: true}, []byte{1}},
+		{NodeCertification{IsDiy: true}, []byte{0}},
+		{NodeCertification{IsCertified: true}, []byte{1}},
+		{FarmCertification{isNotCertified: true}, []byte{0}},
+		{FarmCertification{isGold: true}, []byte{1}},
+		{OptionFarmingPolicyLimit{}, []byte{0}},
+		{OptionFarmingPolicyLimit{HasValue: true, AsValue: FarmingPolicyLimit{
+			FarmingPolicyID:   1,
+			Cu:                types.NewOptionU64(2),
+			NodeCount:         types.NewOptionU32(3),
+			NodeCertification: true,
+		}}, []byte{1, 1, 0, 0, 0, 1, 2, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 3, 0, 0, 0, 1}},
+		{Role{IsNode: true}, []byte{0}},
+		{Role{IsGateway: true}, []byte{1}},
+		{OptionPublicConfig{}, []byte{0}},
+		{OptionPublicConfig{HasValue: true, AsValue: PublicConfig{IPv4: "a", Domain: "d"}}, []byte{1, 4, 'a', 0, 0, 0, 4, 'd'}},
+		{Power{IsUp: true}, []byte{0}},
+		{Power{IsDown: true}, []byte{1}},
+		{PowerState{IsUp: true}, []byte{0}},
+		{PowerState{IsDown: true, AsDown: 5}, []byte{1, 5, 0, 0, 0}},
+		{ValidatorRequestState{IsCreated: true}, []byte{0}},
+		{ValidatorRequestState{IsApproved: true}, []byte{1}},
+		{ValidatorRequestState{IsValidating: true}, []byte{2}},
+	}
+
+	for _, c := range cases {
+		roundTrip(t, c.value, c.data, "%T: %+v", c.value, c.value)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	// types made only of plain fields, enums are covered by TestEnumCodec
+	values := []interface{}{
+		Twin{},
+		Entity{},
+		User{},
+		PublicIP{},
+		PublicConfig{},
+		NodeContract{},
+		Resources{},
+		Location{},
+		Interface{},
+		CertificationCodes{},
+		PricingPolicy{},
+	}
+
+	for _, value := range values {
+		typ := reflect.TypeOf(value)
+		check := func(v interface{}) bool {
+			data, err := types.EncodeToBytes(v)
+			if err != nil {
+				return false
+			}
+
+			decoded := reflect.New(typ)
+			if err := types.DecodeFromBytes(data, decoded.Interface()); err != nil {
+				return false
+			}
+
+			// empty slices are decoded as nil so the encodings are compared
+			again, err := types.EncodeToBytes(decoded.Elem().Interface())
+			return err == nil && bytes.Equal(data, again)
+		}
+
+		config := quick.Config{
+			Values: func(args []reflect.Value, r *rand.Rand) {
+				v, _ := quick.Value(typ, r)
+				args[0] = reflect.ValueOf(v.Interface())
+			},
+		}
+
+		require.NoError(t, quick.Check(check, &config), "%T", value)
+	}
+}
+
+// golden are the versioned types stored on chain by the name of their fixture
+// in testdata/scale. Fixtures are values read from the chain storage with
+// cmd/scalecapture, they start with the chain, block hash and storage key
+// the value was read from. Fixtures are never generated from the types, to
+// update them capture them again.
+var golden = map[string]interface{}{
+	"twin":           Twin{},
+	"farm":           Farm{},
+	"node":           Node{},
+	"entity":         Entity{},
+	"pricing_policy": PricingPolicy{},
+	"farming_policy": FarmingPolicy{},
+	"validator":      Validator{},
+	"node_contract":  Contract{},
+	"name_contract":  Contract{},
+	"rent_contract":  Contract{},
+}
+
+// provenance are the header keys every fixture must have
+var provenance = []string{"chain", "block", "key"}
+
+// fixture is a golden fixture, header holds where it was captured
+type fixture struct {
+	header map[string]string
+	data   []byte
+}
+
+// captured checks that the fixture records where it was read from
+func (f *fixture) captured() error {
+	for _, key := range provenance {
+		if f.header[key] == "" {
+			return fmt.Errorf("fixture has no '%s' provenance header", key)
+		}
+	}
+
+	return nil
+}
+
+// readFixture reads a fixture, a header of '# key: value' lines
+// followed by the hex encoded value
+func readFixture(path string) (fixture, error) {
+	f := fixture{header: map[string]string{}}
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return f, err
+	}
+
+	var value string
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "#") {
+			value += line
+			continue
+		}
+
+		parts := strings.SplitN(strings.TrimPrefix(line, "#"), ":", 2)
+		if len(parts) != 2 {
+			return f, fmt.Errorf("invalid header line '%s'", line)
+		}
+		f.header[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+
+	f.data, err = types.HexDecodeString(value)
+	return f, err
+}
+
+func TestGoldenCorpus(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("testdata", "scale", "*.hex"))
+	require.NoError(t, err)
+
+	if len(paths) == 0 {
+		t.Skip("no fixtures in testdata/scale, capture them with go run ./cmd/scalecapture")
+	}
+
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), ".hex")
+		value, ok := golden[name]
+		require.True(t, ok, "no type for fixture %s", name)
+
+		fixture, err := readFixture(path)
+		require.NoError(t, err, name)
+		require.NoError(t, fixture.captured(), name)
+
+		// the value isn't known upfront, but it must decode with the
+		// current type and encode back to the bytes written by the runtime
+		decoded := reflect.New(reflect.TypeOf(value))
+		require.NoError(t, types.DecodeFromBytes(fixture.data, decoded.Interface()), "%s spec %s", name, fixture.header["spec"])
+
+		encoded, err := types.EncodeToBytes(decoded.Elem().Interface())
+		require.NoError(t, err, name)
+		require.Equal(t, fixture.data, encoded, "%s spec %s", name, fixture.header["spec"])
+	}
+}
+
+func TestReadFixture(t *testing.T) {
+	require := require.New(t)
+
+	path := filepath.Join(t.TempDir(), "fixture.hex")
+	require.NoError(ioutil.WriteFile(path, []byte("# chain: TFChain Devnet\n# block: 0x0102\n# spec: substrate-threefold 147\n# key: 0x0304\n0x0a0b\n"), 0644))
+
+	fixture, err := readFixture(path)
+	require.NoError(err)
+	require.NoError(fixture.captured())
+	require.Equal("0x0102", fixture.header["block"])
+	require.Equal("substrate-threefold 147", fixture.header["spec"])
+	require.Equal([]byte{0x0a, 0x0b}, fixture.data)
+
+	// values without provenance are rejected
+	require.NoError(ioutil.WriteFile(path, []byte("0x0a0b\n"), 0644))
+	fixture, err = readFixture(path)
+	require.NoError(err)
+	require.Error(fixture.captured())
+	require.Equal([]byte{0x0a, 0x0b}, fixture.data)
+}
@@ -0,0 +1,159 @@
+package substrate
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cenkalti/backoff"
+	"github.com/centrifuge/go-substrate-rpc-client/v4/types"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// BlockEvents are the decoded events of a single block
+type BlockEvents struct {
+	Number uint32
+	Hash   types.Hash
+	Events *EventRecords
+}
+
+// SubscribeEvents streams the events of every new block starting from block
+// `from`, or from the next block if from is 0. If finalized is set only finalized
+// blocks are streamed. Blocks are delivered in order without gaps, if the
+// connection is lost the subscription reconnects through the manager that
+// created the client and continues from where it stopped. The subscription
+// uses its own connections, so it outlives the client.
+//
+// Blocks are resolved by number on the best chain of the node when they are
+// fetched. Without finalized a delivered block can later be retracted by a
+// reorg, and a block replaced by a reorg while catching up (for example after
+// a reconnect) is only delivered with the hash of the new best block. Use
+// finalized if every delivered block must stay on the chain.
+//
+// The channel is closed once ctx is done.
+func (s *Substrate) SubscribeEvents(ctx context.Context, from uint32, finalized bool) (<-chan BlockEvents, error) {
+	if s.mgr == nil {
+		return nil, fmt.Errorf("client has no manager to reconnect with")
+	}
+
+	sub := &eventsSubscription{
+		mgr:       s.mgr,
+		finalized: finalized,
+		next:      from,
+		ch:        make(chan BlockEvents),
+	}
+
+	go sub.run(ctx)
+
+	return sub.ch, nil
+}
+
+type eventsSubscription struct {
+	mgr       Manager
+	finalized bool
+	// next block to deliver, 0 if not known yet
+	next uint32
+	ch   chan BlockEvents
+}
+
+func (e *eventsSubscription) run(ctx context.Context) {
+	defer close(e.ch)
+
+	exp := backoff.NewExponentialBackOff()
+	exp.MaxElapsedTime = 0 // retry forever
+	exp.MaxInterval = 30 * time.Second
+
+	for {
+		err := e.follow(ctx, exp)
+		if ctx.Err() != nil {
+			return
+		}
+
+		wait := exp.NextBackOff()
+		log.Error().Err(err).Uint32("next", e.next).Dur("retry-in", wait).Msg("events subscription interrupted")
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// follow streams blocks until the subscription fails
+func (e *eventsSubscription) follow(ctx context.Context, exp backoff.BackOff) error {
+	s, err := e.mgr.SubstrateContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	cl, _, err := s.getClient()
+	if err != nil {
+		return err
+	}
+
+	heads, errs, unsubscribe, err := e.subscribe(cl)
+	if err != nil {
+		return errors.Wrap(err, "failed to subscribe to heads")
+	}
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-errs:
+			return errors.Wrap(err, "heads subscription failed")
+		case head := <-heads:
+			if err := e.catchUp(ctx, cl, s.metas, uint32(head.Number)); err != nil {
+				return err
+			}
+
+			// we are making progress again
+			exp.Reset()
+		}
+	}
+}
+
+func (e *eventsSubscription) subscribe(cl Conn) (<-chan types.Header, <-chan error, func(), error) {
+	method, unsubscribe, notification := "subscribeNewHead", "unsubscribeNewHead", "newHead"
+	if e.finalized {
+		method, unsubscribe, notification = "subscribeFinalizedHeads", "unsubscribeFinalizedHeads", "finalizedHead"
+	}
+
+	ch := make(chan types.Header)
+	sub, err := subscribe(cl, ch, "chain", method, unsubscribe, notification)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return ch, sub.Err(), sub.Unsubscribe, nil
+}
+
+// catchUp delivers all blocks up to and including head
+func (e *eventsSubscription) catchUp(ctx context.Context, cl Conn, cache *metaCache, head uint32) error {
+	if e.next == 0 {
+		e.next = head
+	}
+
+	for ; e.next <= head; e.next++ {
+		hash, err := cl.RPC.Chain.GetBlockHash(uint64(e.next))
+		if err != nil {
+			return errors.Wrapf(err, "failed to get hash of block '%d'", e.next)
+		}
+
+		events, err := eventsAt(cl, cache, hash)
+		if err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case e.ch <- BlockEvents{Number: e.next, Hash: hash, Events: events}:
+		}
+	}
+
+	return nil
+}
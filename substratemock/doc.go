@@ -0,0 +1,11 @@
+// Package substratemock has a mock of the role interfaces of the substrate
+// client, so code depending on them can be unit tested without a chain.
+//
+//	cl := &substratemock.Client{
+//		GetNodeFunc: func(id uint32) (*substrate.Node, error) {
+//			return &substrate.Node{ID: types.U32(id)}, nil
+//		},
+//	}
+package substratemock
+
+//go:generate go run gen.go
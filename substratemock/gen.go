@@ -0,0 +1,269 @@
+//go:build ignore
+// +build ignore
+
+// gen generates mock.go from the interfaces of the substrate package
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"io/ioutil"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	source  = "../interfaces.go"
+	target  = "mock.go"
+	pkg     = "substrate"
+	pkgPath = "github.com/threefoldtech/substrate-client"
+)
+
+type method struct {
+	name    string
+	params  []*ast.Field
+	results []*ast.Field
+}
+
+func main() {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, source, nil, 0)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	imports := map[string]string{}
+	for _, spec := range file.Imports {
+		path, _ := strconv.Unquote(spec.Path.Value)
+		imports[path[strings.LastIndex(path, "/")+1:]] = path
+	}
+
+	interfaces := map[string]*ast.InterfaceType{}
+	var order []string
+	ast.Inspect(file, func(n ast.Node) bool {
+		spec, ok := n.(*ast.TypeSpec)
+		if !ok {
+			return true
+		}
+
+		if iface, ok := spec.Type.(*ast.InterfaceType); ok {
+			interfaces[spec.Name.Name] = iface
+			order = append(order, spec.Name.Name)
+		}
+		return false
+	})
+
+	// methods of all interfaces in declaration order, embedded
+	// interfaces of the same file are expanded
+	var methods []method
+	seen := map[string]bool{}
+	var collect func(iface *ast.InterfaceType)
+	collect = func(iface *ast.InterfaceType) {
+		for _, field := range iface.Methods.List {
+			fn, ok := field.Type.(*ast.FuncType)
+			if !ok {
+				collect(interfaces[field.Type.(*ast.Ident).Name])
+				continue
+			}
+
+			name := field.Names[0].Name
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+
+			m := method{name: name, params: fn.Params.List}
+			if fn.Results != nil {
+				m.results = fn.Results.List
+			}
+			methods = append(methods, m)
+		}
+	}
+
+	for _, name := range order {
+		collect(interfaces[name])
+	}
+
+	used := map[string]bool{}
+	typ := func(expr ast.Expr) string {
+		var buf bytes.Buffer
+		if err := printer.Fprint(&buf, token.NewFileSet(), qualify(expr, used)); err != nil {
+			log.Fatal(err)
+		}
+		return buf.String()
+	}
+
+	var body bytes.Buffer
+	fmt.Fprintf(&body, "// Client is a mock of all the interfaces of the substrate package. Every\n")
+	fmt.Fprintf(&body, "// method calls the function field with the same name and the Func suffix,\n")
+	fmt.Fprintf(&body, "// calling a method which function is not set panics.\n")
+	fmt.Fprintf(&body, "type Client struct {\n")
+	for _, m := range methods {
+		fmt.Fprintf(&body, "%sFunc func%s\n", m.name, signature(m, typ))
+	}
+	fmt.Fprintf(&body, "}\n\n")
+
+	for _, name := range order {
+		fmt.Fprintf(&body, "var _ %s.%s = (*Client)(nil)\n", pkg, name)
+	}
+
+	for _, m := range methods {
+		var args []string
+		for _, p := range m.params {
+			for _, n := range p.Names {
+				if _, ok := p.Type.(*ast.Ellipsis); ok {
+					args = append(args, n.Name+"...")
+				} else {
+					args = append(args, n.Name)
+				}
+			}
+		}
+
+		fmt.Fprintf(&body, "\n// %s implements %s.%s\n", m.name, pkg, owner(m.name, order, interfaces))
+		fmt.Fprintf(&body, "func (m *Client) %s%s {\n", m.name, signature(m, typ))
+		fmt.Fprintf(&body, "if m.%sFunc == nil {\npanic(\"substratemock: unexpected call to %s\")\n}\n", m.name, m.name)
+		call := fmt.Sprintf("m.%sFunc(%s)", m.name, strings.Join(args, ", "))
+		if len(m.results) == 0 {
+			fmt.Fprintf(&body, "%s\n}\n", call)
+		} else {
+			fmt.Fprintf(&body, "return %s\n}\n", call)
+		}
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by gen.go; DO NOT EDIT.\n\n")
+	fmt.Fprintf(&out, "package substratemock\n\nimport (\n")
+
+	names := []string{pkg}
+	for name := range used {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return path(names[i], imports) < path(names[j], imports)
+	})
+
+	// standard library imports go first
+	var std, other bytes.Buffer
+	for _, name := range names {
+		p := path(name, imports)
+		w := &other
+		if !strings.Contains(strings.Split(p, "/")[0], ".") {
+			w = &std
+		}
+
+		if p[strings.LastIndex(p, "/")+1:] != name {
+			fmt.Fprintf(w, "%s %q\n", name, p)
+		} else {
+			fmt.Fprintf(w, "%q\n", p)
+		}
+	}
+	out.Write(std.Bytes())
+	out.WriteString("\n")
+	out.Write(other.Bytes())
+	fmt.Fprintf(&out, ")\n\n")
+	out.Write(body.Bytes())
+
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(target, src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func path(name string, imports map[string]string) string {
+	if name == pkg {
+		return pkgPath
+	}
+	return imports[name]
+}
+
+// owner returns the first interface that declares the method
+func owner(name string, order []string, interfaces map[string]*ast.InterfaceType) string {
+	for _, iface := range order {
+		for _, field := range interfaces[iface].Methods.List {
+			if len(field.Names) != 0 && field.Names[0].Name == name {
+				return iface
+			}
+		}
+	}
+
+	return ""
+}
+
+// signature prints the params and results of the method
+func signature(m method, typ func(ast.Expr) string) string {
+	var params []string
+	for _, p := range m.params {
+		var names []string
+		for _, n := range p.Names {
+			names = append(names, n.Name)
+		}
+		params = append(params, fmt.Sprintf("%s %s", strings.Join(names, ", "), typ(p.Type)))
+	}
+
+	var results []string
+	for _, r := range m.results {
+		results = append(results, typ(r.Type))
+	}
+
+	sig := fmt.Sprintf("(%s)", strings.Join(params, ", "))
+	switch len(results) {
+	case 0:
+	case 1:
+		sig += " " + results[0]
+	default:
+		sig += fmt.Sprintf(" (%s)", strings.Join(results, ", "))
+	}
+
+	return sig
+}
+
+// qualify prefixes the types of the substrate package with the package
+// name and records the packages used by the expression
+func qualify(expr ast.Expr, used map[string]bool) ast.Expr {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		if ast.IsExported(e.Name) {
+			return &ast.SelectorExpr{X: ast.NewIdent(pkg), Sel: e}
+		}
+		return e
+	case *ast.SelectorExpr:
+		used[e.X.(*ast.Ident).Name] = true
+		return e
+	case *ast.StarExpr:
+		return &ast.StarExpr{X: qualify(e.X, used)}
+	case *ast.ArrayType:
+		return &ast.ArrayType{Len: e.Len, Elt: qualify(e.Elt, used)}
+	case *ast.Ellipsis:
+		return &ast.Ellipsis{Elt: qualify(e.Elt, used)}
+	case *ast.ChanType:
+		return &ast.ChanType{Dir: e.Dir, Value: qualify(e.Value, used)}
+	case *ast.MapType:
+		return &ast.MapType{Key: qualify(e.Key, used), Value: qualify(e.Value, used)}
+	case *ast.FuncType:
+		fn := &ast.FuncType{Params: &ast.FieldList{}}
+		for _, p := range e.Params.List {
+			fn.Params.List = append(fn.Params.List, &ast.Field{Names: p.Names, Type: qualify(p.Type, used)})
+		}
+		if e.Results != nil {
+			fn.Results = &ast.FieldList{}
+			for _, r := range e.Results.List {
+				fn.Results.List = append(fn.Results.List, &ast.Field{Names: r.Names, Type: qualify(r.Type, used)})
+			}
+		}
+		return fn
+	}
+
+	log.Fatalf("unsupported type expression %T", expr)
+	return nil
+}
@@ -0,0 +1,553 @@
+// Code generated by gen.go; DO NOT EDIT.
+
+package substratemock
+
+import (
+	"context"
+	"math/big"
+	"net"
+	"time"
+
+	"github.com/centrifuge/go-substrate-rpc-client/v4/types"
+	substrate "github.com/threefoldtech/substrate-client"
+)
+
+// Client is a mock of all the interfaces of the substrate package. Every
+// method calls the function field with the same name and the Func suffix,
+// calling a method which function is not set panics.
+type Client struct {
+	GetTwinFunc                         func(id uint32) (*substrate.Twin, error)
+	GetTwinByPubKeyFunc                 func(pk []byte) (uint32, error)
+	CreateTwinFunc                      func(identity substrate.Identity, ip net.IP) (uint32, error)
+	UpdateTwinFunc                      func(identity substrate.Identity, ip net.IP) (uint32, error)
+	AcceptTermsAndConditionsFunc        func(identity substrate.Identity, documentLink string, documentHash string) error
+	SignedTermsAndConditionsFunc        func(account substrate.AccountID) ([]substrate.TermsAndConditions, error)
+	GetNodeFunc                         func(id uint32) (*substrate.Node, error)
+	GetNodeByTwinIDFunc                 func(twin uint32) (uint32, error)
+	GetLastNodeIDFunc                   func() (uint32, error)
+	ScanNodesFunc                       func(ctx context.Context, from, to uint32) (<-chan substrate.ScannedNode, error)
+	GetFarmFunc                         func(id uint32) (*substrate.Farm, error)
+	GetEntityFunc                       func(id uint32) (*substrate.Entity, error)
+	CreateNodeFunc                      func(identity substrate.Identity, node substrate.Node) (uint32, error)
+	UpdateNodeFunc                      func(identity substrate.Identity, node substrate.Node) (uint32, error)
+	UpdateNodeUptimeFunc                func(identity substrate.Identity, uptime uint64) (types.Hash, error)
+	SetNodeCertificateFunc              func(sudo substrate.Identity, id uint32, cert substrate.NodeCertification) error
+	GetContractFunc                     func(id uint64) (*substrate.Contract, error)
+	GetContractWithHashFunc             func(node uint32, hash string) (uint64, error)
+	GetNodeRentContractFunc             func(node uint32) (uint64, error)
+	GetContractIDByNameRegistrationFunc func(name string) (uint64, error)
+	GetNodeContractsFunc                func(node uint32) ([]types.U64, error)
+	CreateNodeContractFunc              func(identity substrate.Identity, node uint32, body []byte, hash string, publicIPs uint32) (uint64, error)
+	CreateNameContractFunc              func(identity substrate.Identity, name string) (uint64, error)
+	CreateRentContractFunc              func(identity substrate.Identity, node uint32) (uint64, error)
+	UpdateNodeContractFunc              func(identity substrate.Identity, contract uint64, body []byte, hash string) (uint64, error)
+	CancelContractFunc                  func(identity substrate.Identity, contract uint64) error
+	SetContractConsumptionFunc          func(identity substrate.Identity, resources ...substrate.ContractResources) error
+	ReportFunc                          func(identity substrate.Identity, consumptions []substrate.NruConsumption) (types.Hash, error)
+	IsValidatorFunc                     func(identity substrate.Identity) (bool, error)
+	IsMintedAlreadyFunc                 func(identity substrate.Identity, mintTxID string) (bool, error)
+	ProposeOrVoteMintTransactionFunc    func(identity substrate.Identity, txID string, target substrate.AccountID, amount *big.Int) (*types.Call, error)
+	IsBurnedAlreadyFunc                 func(identity substrate.Identity, burnTransactionID types.U64) (bool, error)
+	GetBurnTransactionFunc              func(identity substrate.Identity, burnTransactionID types.U64) (*substrate.BurnTransaction, error)
+	ProposeBurnTransactionOrAddSigFunc  func(identity substrate.Identity, txID uint64, target string, amount *big.Int, signature string, stellarAddress string, sequenceNumber uint64) (*types.Call, error)
+	SetBurnTransactionExecutedFunc      func(identity substrate.Identity, txID uint64) (*types.Call, error)
+	IsRefundedAlreadyFunc               func(identity substrate.Identity, txHash string) (bool, error)
+	GetRefundTransactionFunc            func(identity substrate.Identity, txHash string) (*substrate.RefundTransaction, error)
+	CreateRefundTransactionOrAddSigFunc func(identity substrate.Identity, txHash string, target string, amount int64, signature string, stellarAddress string, sequenceNumber uint64) (*types.Call, error)
+	SetRefundTransactionExecutedFunc    func(identity substrate.Identity, txHash string) (*types.Call, error)
+	GetCurrentHeightFunc                func() (uint32, error)
+	GetBlockFunc                        func(block types.Hash) (*types.SignedBlock, error)
+	GetEventsForBlockFunc               func(height uint32) (*substrate.EventRecords, error)
+	GetEventsForBlockRangeFunc          func(start uint32, end uint32) ([]substrate.BlockEvents, error)
+	GetBlockInfoFunc                    func(hash types.Hash) (*substrate.BlockInfo, error)
+	GetBlockInfoAtFunc                  func(number uint32) (*substrate.BlockInfo, error)
+	IterateBlocksFunc                   func(from, to uint32, fn func(block *substrate.BlockInfo) error) error
+	SubscribeEventsFunc                 func(ctx context.Context, from uint32, finalized bool) (<-chan substrate.BlockEvents, error)
+	TimeFunc                            func() (time.Time, error)
+	BlockTimeFunc                       func(height uint32) (time.Time, error)
+	BlockAtFunc                         func(t time.Time) (uint32, error)
+	EnsureAccountFunc                   func(identity substrate.Identity, activationURL, termsAndConditionsLink, termsAndConditionsHash string) (types.AccountInfo, error)
+	GetAccountFunc                      func(identity substrate.Identity) (types.AccountInfo, error)
+	GetDepositFeeFunc                   func(identity substrate.Identity) (int64, error)
+	BatchFunc                           func(identity substrate.Identity, calls []types.Call, opts ...substrate.CallOption) (substrate.BatchResult, error)
+	BatchAllFunc                        func(identity substrate.Identity, calls []types.Call, opts ...substrate.CallOption) (substrate.BatchResult, error)
+	EstimateFeeFunc                     func(identity substrate.Identity, call types.Call, opts ...substrate.CallOption) (*big.Int, error)
+	DryRunFunc                          func(identity substrate.Identity, call types.Call, opts ...substrate.CallOption) error
+	CloseFunc                           func()
+}
+
+var _ substrate.TwinManager = (*Client)(nil)
+var _ substrate.NodeReader = (*Client)(nil)
+var _ substrate.NodeWriter = (*Client)(nil)
+var _ substrate.ContractReader = (*Client)(nil)
+var _ substrate.ContractWriter = (*Client)(nil)
+var _ substrate.BridgeValidator = (*Client)(nil)
+var _ substrate.EventSource = (*Client)(nil)
+var _ substrate.Clock = (*Client)(nil)
+var _ substrate.AccountManager = (*Client)(nil)
+var _ substrate.Transactor = (*Client)(nil)
+var _ substrate.Client = (*Client)(nil)
+
+// GetTwin implements substrate.TwinManager
+func (m *Client) GetTwin(id uint32) (*substrate.Twin, error) {
+	if m.GetTwinFunc == nil {
+		panic("substratemock: unexpected call to GetTwin")
+	}
+	return m.GetTwinFunc(id)
+}
+
+// GetTwinByPubKey implements substrate.TwinManager
+func (m *Client) GetTwinByPubKey(pk []byte) (uint32, error) {
+	if m.GetTwinByPubKeyFunc == nil {
+		panic("substratemock: unexpected call to GetTwinByPubKey")
+	}
+	return m.GetTwinByPubKeyFunc(pk)
+}
+
+// CreateTwin implements substrate.TwinManager
+func (m *Client) CreateTwin(identity substrate.Identity, ip net.IP) (uint32, error) {
+	if m.CreateTwinFunc == nil {
+		panic("substratemock: unexpected call to CreateTwin")
+	}
+	return m.CreateTwinFunc(identity, ip)
+}
+
+// UpdateTwin implements substrate.TwinManager
+func (m *Client) UpdateTwin(identity substrate.Identity, ip net.IP) (uint32, error) {
+	if m.UpdateTwinFunc == nil {
+		panic("substratemock: unexpected call to UpdateTwin")
+	}
+	return m.UpdateTwinFunc(identity, ip)
+}
+
+// AcceptTermsAndConditions implements substrate.TwinManager
+func (m *Client) AcceptTermsAndConditions(identity substrate.Identity, documentLink string, documentHash string) error {
+	if m.AcceptTermsAndConditionsFunc == nil {
+		panic("substratemock: unexpected call to AcceptTermsAndConditions")
+	}
+	return m.AcceptTermsAndConditionsFunc(identity, documentLink, documentHash)
+}
+
+// SignedTermsAndConditions implements substrate.TwinManager
+func (m *Client) SignedTermsAndConditions(account substrate.AccountID) ([]substrate.TermsAndConditions, error) {
+	if m.SignedTermsAndConditionsFunc == nil {
+		panic("substratemock: unexpected call to SignedTermsAndConditions")
+	}
+	return m.SignedTermsAndConditionsFunc(account)
+}
+
+// GetNode implements substrate.NodeReader
+func (m *Client) GetNode(id uint32) (*substrate.Node, error) {
+	if m.GetNodeFunc == nil {
+		panic("substratemock: unexpected call to GetNode")
+	}
+	return m.GetNodeFunc(id)
+}
+
+// GetNodeByTwinID implements substrate.NodeReader
+func (m *Client) GetNodeByTwinID(twin uint32) (uint32, error) {
+	if m.GetNodeByTwinIDFunc == nil {
+		panic("substratemock: unexpected call to GetNodeByTwinID")
+	}
+	return m.GetNodeByTwinIDFunc(twin)
+}
+
+// GetLastNodeID implements substrate.NodeReader
+func (m *Client) GetLastNodeID() (uint32, error) {
+	if m.GetLastNodeIDFunc == nil {
+		panic("substratemock: unexpected call to GetLastNodeID")
+	}
+	return m.GetLastNodeIDFunc()
+}
+
+// ScanNodes implements substrate.NodeReader
+func (m *Client) ScanNodes(ctx context.Context, from, to uint32) (<-chan substrate.ScannedNode, error) {
+	if m.ScanNodesFunc == nil {
+		panic("substratemock: unexpected call to ScanNodes")
+	}
+	return m.ScanNodesFunc(ctx, from, to)
+}
+
+// GetFarm implements substrate.NodeReader
+func (m *Client) GetFarm(id uint32) (*substrate.Farm, error) {
+	if m.GetFarmFunc == nil {
+		panic("substratemock: unexpected call to GetFarm")
+	}
+	return m.GetFarmFunc(id)
+}
+
+// GetEntity implements substrate.NodeReader
+func (m *Client) GetEntity(id uint32) (*substrate.Entity, error) {
+	if m.GetEntityFunc == nil {
+		panic("substratemock: unexpected call to GetEntity")
+	}
+	return m.GetEntityFunc(id)
+}
+
+// CreateNode implements substrate.NodeWriter
+func (m *Client) CreateNode(identity substrate.Identity, node substrate.Node) (uint32, error) {
+	if m.CreateNodeFunc == nil {
+		panic("substratemock: unexpected call to CreateNode")
+	}
+	return m.CreateNodeFunc(identity, node)
+}
+
+// UpdateNode implements substrate.NodeWriter
+func (m *Client) UpdateNode(identity substrate.Identity, node substrate.Node) (uint32, error) {
+	if m.UpdateNodeFunc == nil {
+		panic("substratemock: unexpected call to UpdateNode")
+	}
+	return m.UpdateNodeFunc(identity, node)
+}
+
+// UpdateNodeUptime implements substrate.NodeWriter
+func (m *Client) UpdateNodeUptime(identity substrate.Identity, uptime uint64) (types.Hash, error) {
+	if m.UpdateNodeUptimeFunc == nil {
+		panic("substratemock: unexpected call to UpdateNodeUptime")
+	}
+	return m.UpdateNodeUptimeFunc(identity, uptime)
+}
+
+// SetNodeCertificate implements substrate.NodeWriter
+func (m *Client) SetNodeCertificate(sudo substrate.Identity, id uint32, cert substrate.NodeCertification) error {
+	if m.SetNodeCertificateFunc == nil {
+		panic("substratemock: unexpected call to SetNodeCertificate")
+	}
+	return m.SetNodeCertificateFunc(sudo, id, cert)
+}
+
+// GetContract implements substrate.ContractReader
+func (m *Client) GetContract(id uint64) (*substrate.Contract, error) {
+	if m.GetContractFunc == nil {
+		panic("substratemock: unexpected call to GetContract")
+	}
+	return m.GetContractFunc(id)
+}
+
+// GetContractWithHash implements substrate.ContractReader
+func (m *Client) GetContractWithHash(node uint32, hash string) (uint64, error) {
+	if m.GetContractWithHashFunc == nil {
+		panic("substratemock: unexpected call to GetContractWithHash")
+	}
+	return m.GetContractWithHashFunc(node, hash)
+}
+
+// GetNodeRentContract implements substrate.ContractReader
+func (m *Client) GetNodeRentContract(node uint32) (uint64, error) {
+	if m.GetNodeRentContractFunc == nil {
+		panic("substratemock: unexpected call to GetNodeRentContract")
+	}
+	return m.GetNodeRentContractFunc(node)
+}
+
+// GetContractIDByNameRegistration implements substrate.ContractReader
+func (m *Client) GetContractIDByNameRegistration(name string) (uint64, error) {
+	if m.GetContractIDByNameRegistrationFunc == nil {
+		panic("substratemock: unexpected call to GetContractIDByNameRegistration")
+	}
+	return m.GetContractIDByNameRegistrationFunc(name)
+}
+
+// GetNodeContracts implements substrate.ContractReader
+func (m *Client) GetNodeContracts(node uint32) ([]types.U64, error) {
+	if m.GetNodeContractsFunc == nil {
+		panic("substratemock: unexpected call to GetNodeContracts")
+	}
+	return m.GetNodeContractsFunc(node)
+}
+
+// CreateNodeContract implements substrate.ContractWriter
+func (m *Client) CreateNodeContract(identity substrate.Identity, node uint32, body []byte, hash string, publicIPs uint32) (uint64, error) {
+	if m.CreateNodeContractFunc == nil {
+		panic("substratemock: unexpected call to CreateNodeContract")
+	}
+	return m.CreateNodeContractFunc(identity, node, body, hash, publicIPs)
+}
+
+// CreateNameContract implements substrate.ContractWriter
+func (m *Client) CreateNameContract(identity substrate.Identity, name string) (uint64, error) {
+	if m.CreateNameContractFunc == nil {
+		panic("substratemock: unexpected call to CreateNameContract")
+	}
+	return m.CreateNameContractFunc(identity, name)
+}
+
+// CreateRentContract implements substrate.ContractWriter
+func (m *Client) CreateRentContract(identity substrate.Identity, node uint32) (uint64, error) {
+	if m.CreateRentContractFunc == nil {
+		panic("substratemock: unexpected call to CreateRentContract")
+	}
+	return m.CreateRentContractFunc(identity, node)
+}
+
+// UpdateNodeContract implements substrate.ContractWriter
+func (m *Client) UpdateNodeContract(identity substrate.Identity, contract uint64, body []byte, hash string) (uint64, error) {
+	if m.UpdateNodeContractFunc == nil {
+		panic("substratemock: unexpected call to UpdateNodeContract")
+	}
+	return m.UpdateNodeContractFunc(identity, contract, body, hash)
+}
+
+// CancelContract implements substrate.ContractWriter
+func (m *Client) CancelContract(identity substrate.Identity, contract uint64) error {
+	if m.CancelContractFunc == nil {
+		panic("substratemock: unexpected call to CancelContract")
+	}
+	return m.CancelContractFunc(identity, contract)
+}
+
+// SetContractConsumption implements substrate.ContractWriter
+func (m *Client) SetContractConsumption(identity substrate.Identity, resources ...substrate.ContractResources) error {
+	if m.SetContractConsumptionFunc == nil {
+		panic("substratemock: unexpected call to SetContractConsumption")
+	}
+	return m.SetContractConsumptionFunc(identity, resources...)
+}
+
+// Report implements substrate.ContractWriter
+func (m *Client) Report(identity substrate.Identity, consumptions []substrate.NruConsumption) (types.Hash, error) {
+	if m.ReportFunc == nil {
+		panic("substratemock: unexpected call to Report")
+	}
+	return m.ReportFunc(identity, consumptions)
+}
+
+// IsValidator implements substrate.BridgeValidator
+func (m *Client) IsValidator(identity substrate.Identity) (bool, error) {
+	if m.IsValidatorFunc == nil {
+		panic("substratemock: unexpected call to IsValidator")
+	}
+	return m.IsValidatorFunc(identity)
+}
+
+// IsMintedAlready implements substrate.BridgeValidator
+func (m *Client) IsMintedAlready(identity substrate.Identity, mintTxID string) (bool, error) {
+	if m.IsMintedAlreadyFunc == nil {
+		panic("substratemock: unexpected call to IsMintedAlready")
+	}
+	return m.IsMintedAlreadyFunc(identity, mintTxID)
+}
+
+// ProposeOrVoteMintTransaction implements substrate.BridgeValidator
+func (m *Client) ProposeOrVoteMintTransaction(identity substrate.Identity, txID string, target substrate.AccountID, amount *big.Int) (*types.Call, error) {
+	if m.ProposeOrVoteMintTransactionFunc == nil {
+		panic("substratemock: unexpected call to ProposeOrVoteMintTransaction")
+	}
+	return m.ProposeOrVoteMintTransactionFunc(identity, txID, target, amount)
+}
+
+// IsBurnedAlready implements substrate.BridgeValidator
+func (m *Client) IsBurnedAlready(identity substrate.Identity, burnTransactionID types.U64) (bool, error) {
+	if m.IsBurnedAlreadyFunc == nil {
+		panic("substratemock: unexpected call to IsBurnedAlready")
+	}
+	return m.IsBurnedAlreadyFunc(identity, burnTransactionID)
+}
+
+// GetBurnTransaction implements substrate.BridgeValidator
+func (m *Client) GetBurnTransaction(identity substrate.Identity, burnTransactionID types.U64) (*substrate.BurnTransaction, error) {
+	if m.GetBurnTransactionFunc == nil {
+		panic("substratemock: unexpected call to GetBurnTransaction")
+	}
+	return m.GetBurnTransactionFunc(identity, burnTransactionID)
+}
+
+// ProposeBurnTransactionOrAddSig implements substrate.BridgeValidator
+func (m *Client) ProposeBurnTransactionOrAddSig(identity substrate.Identity, txID uint64, target string, amount *big.Int, signature string, stellarAddress string, sequenceNumber uint64) (*types.Call, error) {
+	if m.ProposeBurnTransactionOrAddSigFunc == nil {
+		panic("substratemock: unexpected call to ProposeBurnTransactionOrAddSig")
+	}
+	return m.ProposeBurnTransactionOrAddSigFunc(identity, txID, target, amount, signature, stellarAddress, sequenceNumber)
+}
+
+// SetBurnTransactionExecuted implements substrate.BridgeValidator
+func (m *Client) SetBurnTransactionExecuted(identity substrate.Identity, txID uint64) (*types.Call, error) {
+	if m.SetBurnTransactionExecutedFunc == nil {
+		panic("substratemock: unexpected call to SetBurnTransactionExecuted")
+	}
+	return m.SetBurnTransactionExecutedFunc(identity, txID)
+}
+
+// IsRefundedAlready implements substrate.BridgeValidator
+func (m *Client) IsRefundedAlready(identity substrate.Identity, txHash string) (bool, error) {
+	if m.IsRefundedAlreadyFunc == nil {
+		panic("substratemock: unexpected call to IsRefundedAlready")
+	}
+	return m.IsRefundedAlreadyFunc(identity, txHash)
+}
+
+// GetRefundTransaction implements substrate.BridgeValidator
+func (m *Client) GetRefundTransaction(identity substrate.Identity, txHash string) (*substrate.RefundTransaction, error) {
+	if m.GetRefundTransactionFunc == nil {
+		panic("substratemock: unexpected call to GetRefundTransaction")
+	}
+	return m.GetRefundTransactionFunc(identity, txHash)
+}
+
+// CreateRefundTransactionOrAddSig implements substrate.BridgeValidator
+func (m *Client) CreateRefundTransactionOrAddSig(identity substrate.Identity, txHash string, target string, amount int64, signature string, stellarAddress string, sequenceNumber uint64) (*types.Call, error) {
+	if m.CreateRefundTransactionOrAddSigFunc == nil {
+		panic("substratemock: unexpected call to CreateRefundTransactionOrAddSig")
+	}
+	return m.CreateRefundTransactionOrAddSigFunc(identity, txHash, target, amount, signature, stellarAddress, sequenceNumber)
+}
+
+// SetRefundTransactionExecuted implements substrate.BridgeValidator
+func (m *Client) SetRefundTransactionExecuted(identity substrate.Identity, txHash string) (*types.Call, error) {
+	if m.SetRefundTransactionExecutedFunc == nil {
+		panic("substratemock: unexpected call to SetRefundTransactionExecuted")
+	}
+	return m.SetRefundTransactionExecutedFunc(identity, txHash)
+}
+
+// GetCurrentHeight implements substrate.EventSource
+func (m *Client) GetCurrentHeight() (uint32, error) {
+	if m.GetCurrentHeightFunc == nil {
+		panic("substratemock: unexpected call to GetCurrentHeight")
+	}
+	return m.GetCurrentHeightFunc()
+}
+
+// GetBlock implements substrate.EventSource
+func (m *Client) GetBlock(block types.Hash) (*types.SignedBlock, error) {
+	if m.GetBlockFunc == nil {
+		panic("substratemock: unexpected call to GetBlock")
+	}
+	return m.GetBlockFunc(block)
+}
+
+// GetEventsForBlock implements substrate.EventSource
+func (m *Client) GetEventsForBlock(height uint32) (*substrate.EventRecords, error) {
+	if m.GetEventsForBlockFunc == nil {
+		panic("substratemock: unexpected call to GetEventsForBlock")
+	}
+	return m.GetEventsForBlockFunc(height)
+}
+
+// GetEventsForBlockRange implements substrate.EventSource
+func (m *Client) GetEventsForBlockRange(start uint32, end uint32) ([]substrate.BlockEvents, error) {
+	if m.GetEventsForBlockRangeFunc == nil {
+		panic("substratemock: unexpected call to GetEventsForBlockRange")
+	}
+	return m.GetEventsForBlockRangeFunc(start, end)
+}
+
+// GetBlockInfo implements substrate.EventSource
+func (m *Client) GetBlockInfo(hash types.Hash) (*substrate.BlockInfo, error) {
+	if m.GetBlockInfoFunc == nil {
+		panic("substratemock: unexpected call to GetBlockInfo")
+	}
+	return m.GetBlockInfoFunc(hash)
+}
+
+// GetBlockInfoAt implements substrate.EventSource
+func (m *Client) GetBlockInfoAt(number uint32) (*substrate.BlockInfo, error) {
+	if m.GetBlockInfoAtFunc == nil {
+		panic("substratemock: unexpected call to GetBlockInfoAt")
+	}
+	return m.GetBlockInfoAtFunc(number)
+}
+
+// IterateBlocks implements substrate.EventSource
+func (m *Client) IterateBlocks(from, to uint32, fn func(block *substrate.BlockInfo) error) error {
+	if m.IterateBlocksFunc == nil {
+		panic("substratemock: unexpected call to IterateBlocks")
+	}
+	return m.IterateBlocksFunc(from, to, fn)
+}
+
+// SubscribeEvents implements substrate.EventSource
+func (m *Client) SubscribeEvents(ctx context.Context, from uint32, finalized bool) (<-chan substrate.BlockEvents, error) {
+	if m.SubscribeEventsFunc == nil {
+		panic("substratemock: unexpected call to SubscribeEvents")
+	}
+	return m.SubscribeEventsFunc(ctx, from, finalized)
+}
+
+// Time implements substrate.Clock
+func (m *Client) Time() (time.Time, error) {
+	if m.TimeFunc == nil {
+		panic("substratemock: unexpected call to Time")
+	}
+	return m.TimeFunc()
+}
+
+// BlockTime implements substrate.Clock
+func (m *Client) BlockTime(height uint32) (time.Time, error) {
+	if m.BlockTimeFunc == nil {
+		panic("substratemock: unexpected call to BlockTime")
+	}
+	return m.BlockTimeFunc(height)
+}
+
+// BlockAt implements substrate.Clock
+func (m *Client) BlockAt(t time.Time) (uint32, error) {
+	if m.BlockAtFunc == nil {
+		panic("substratemock: unexpected call to BlockAt")
+	}
+	return m.BlockAtFunc(t)
+}
+
+// EnsureAccount implements substrate.AccountManager
+func (m *Client) EnsureAccount(identity substrate.Identity, activationURL, termsAndConditionsLink, termsAndConditionsHash string) (types.AccountInfo, error) {
+	if m.EnsureAccountFunc == nil {
+		panic("substratemock: unexpected call to EnsureAccount")
+	}
+	return m.EnsureAccountFunc(identity, activationURL, termsAndConditionsLink, termsAndConditionsHash)
+}
+
+// GetAccount implements substrate.AccountManager
+func (m *Client) GetAccount(identity substrate.Identity) (types.AccountInfo, error) {
+	if m.GetAccountFunc == nil {
+		panic("substratemock: unexpected call to GetAccount")
+	}
+	return m.GetAccountFunc(identity)
+}
+
+// GetDepositFee implements substrate.AccountManager
+func (m *Client) GetDepositFee(identity substrate.Identity) (int64, error) {
+	if m.GetDepositFeeFunc == nil {
+		panic("substratemock: unexpected call to GetDepositFee")
+	}
+	return m.GetDepositFeeFunc(identity)
+}
+
+// Batch implements substrate.Transactor
+func (m *Client) Batch(identity substrate.Identity, calls []types.Call, opts ...substrate.CallOption) (substrate.BatchResult, error) {
+	if m.BatchFunc == nil {
+		panic("substratemock: unexpected call to Batch")
+	}
+	return m.BatchFunc(identity, calls, opts...)
+}
+
+// BatchAll implements substrate.Transactor
+func (m *Client) BatchAll(identity substrate.Identity, calls []types.Call, opts ...substrate.CallOption) (substrate.BatchResult, error) {
+	if m.BatchAllFunc == nil {
+		panic("substratemock: unexpected call to BatchAll")
+	}
+	return m.BatchAllFunc(identity, calls, opts...)
+}
+
+// EstimateFee implements substrate.Transactor
+func (m *Client) EstimateFee(identity substrate.Identity, call types.Call, opts ...substrate.CallOption) (*big.Int, error) {
+	if m.EstimateFeeFunc == nil {
+		panic("substratemock: unexpected call to EstimateFee")
+	}
+	return m.EstimateFeeFunc(identity, call, opts...)
+}
+
+// DryRun implements substrate.Transactor
+func (m *Client) DryRun(identity substrate.Identity, call types.Call, opts ...substrate.CallOption) error {
+	if m.DryRunFunc == nil {
+		panic("substratemock: unexpected call to DryRun")
+	}
+	return m.DryRunFunc(identity, call, opts...)
+}
+
+// Close implements substrate.Client
+func (m *Client) Close() {
+	if m.CloseFunc == nil {
+		panic("substratemock: unexpected call to Close")
+	}
+	m.CloseFunc()
+}
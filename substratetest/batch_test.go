@@ -0,0 +1,115 @@
+package substratetest
+
+import (
+	"testing"
+
+	"github.com/centrifuge/go-substrate-rpc-client/v4/types"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	substrate "github.com/threefoldtech/substrate-client"
+)
+
+func TestBatch(t *testing.T) {
+	require := require.New(t)
+
+	srv, err := NewServer()
+	require.NoError(err)
+	defer srv.Close()
+
+	mgr := substrate.NewManager(srv.URL())
+	defer mgr.Close()
+
+	cl, err := mgr.Substrate()
+	require.NoError(err)
+	defer cl.Close()
+
+	user, err := substrate.NewIdentityFromSr25519Phrase("//Bob")
+	require.NoError(err)
+
+	_, meta, err := cl.GetClient()
+	require.NoError(err)
+
+	accept, err := types.NewCall(meta, "TfgridModule.user_accept_tc", "link", "hash")
+	require.NoError(err)
+
+	create, err := types.NewCall(meta, "TfgridModule.create_twin", "::1")
+	require.NoError(err)
+
+	// the second twin fails, so the last call is not executed
+	result, err := cl.Batch(user, []types.Call{accept, create, create, accept})
+	require.NoError(err)
+	require.True(result.Success)
+	require.Len(result.Calls, 4)
+
+	require.NoError(result.Calls[0].Error)
+	require.Empty(result.Calls[0].Events.TfgridModule_TwinStored)
+
+	require.NoError(result.Calls[1].Error)
+	require.Len(result.Calls[1].Events.TfgridModule_TwinStored, 1)
+	require.EqualValues(1, result.Calls[1].Events.TfgridModule_TwinStored[0].Twin.ID)
+
+	var de *substrate.DispatchError
+	require.True(errors.As(result.Calls[2].Error, &de))
+	require.Equal("TwinWithSameAccountIdExists", de.Name)
+	require.Empty(result.Calls[2].Events.TfgridModule_TwinStored)
+
+	require.ErrorIs(result.Calls[3].Error, substrate.ErrNotExecuted)
+
+	// calls before the failed one are not reverted
+	twin, err := cl.GetTwinByPubKey(user.PublicKey())
+	require.NoError(err)
+	require.EqualValues(1, twin)
+}
+
+func TestBatchAll(t *testing.T) {
+	require := require.New(t)
+
+	srv, err := NewServer()
+	require.NoError(err)
+	defer srv.Close()
+
+	mgr := substrate.NewManager(srv.URL())
+	defer mgr.Close()
+
+	cl, err := mgr.Substrate()
+	require.NoError(err)
+	defer cl.Close()
+
+	user, err := substrate.NewIdentityFromSr25519Phrase("//Bob")
+	require.NoError(err)
+
+	_, meta, err := cl.GetClient()
+	require.NoError(err)
+
+	accept, err := types.NewCall(meta, "TfgridModule.user_accept_tc", "link", "hash")
+	require.NoError(err)
+
+	create, err := types.NewCall(meta, "TfgridModule.create_twin", "::1")
+	require.NoError(err)
+
+	// the second twin fails, so the whole batch is reverted
+	result, err := cl.BatchAll(user, []types.Call{accept, create, create})
+	require.Error(err)
+	require.False(result.Success)
+	require.Len(result.Calls, 3)
+
+	var de *substrate.DispatchError
+	require.True(errors.As(err, &de))
+	require.Equal("TwinWithSameAccountIdExists", de.Name)
+	for _, call := range result.Calls {
+		require.True(errors.As(call.Error, &de))
+		require.Empty(call.Events.TfgridModule_TwinStored)
+	}
+
+	_, err = cl.GetTwinByPubKey(user.PublicKey())
+	require.ErrorIs(err, substrate.ErrNotFound)
+
+	result, err = cl.BatchAll(user, []types.Call{accept, create})
+	require.NoError(err)
+	require.True(result.Success)
+	require.Len(result.Calls, 2)
+
+	require.NoError(result.Calls[0].Error)
+	require.NoError(result.Calls[1].Error)
+	require.Len(result.Calls[1].Events.TfgridModule_TwinStored, 1)
+}
@@ -0,0 +1,97 @@
+package substratetest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+	substrate "github.com/threefoldtech/substrate-client"
+)
+
+// rpcCalls returns the number of calls made to the rpc method
+func rpcCalls(t *testing.T, reg *prometheus.Registry, method string) uint64 {
+	families, err := reg.Gather()
+	require.NoError(t, err)
+
+	var calls uint64
+	for _, family := range families {
+		if family.GetName() != "substrate_client_rpc_duration_seconds" {
+			continue
+		}
+
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "method" && label.GetValue() == method {
+					calls += metric.GetHistogram().GetSampleCount()
+				}
+			}
+		}
+	}
+
+	return calls
+}
+
+func TestBlockTime(t *testing.T) {
+	require := require.New(t)
+
+	srv, err := NewServer(WithBlockTime(time.Hour))
+	require.NoError(err)
+	defer srv.Close()
+
+	for i := 0; i < 20; i++ {
+		require.NoError(srv.NewBlock())
+	}
+	head := srv.Height()
+
+	reg := prometheus.NewRegistry()
+	metrics, err := substrate.NewMetrics(reg)
+	require.NoError(err)
+
+	mgr := substrate.NewManagerWithOptions([]string{srv.URL()}, substrate.WithMetrics(metrics))
+	defer mgr.Close()
+
+	cl, err := mgr.Substrate()
+	require.NoError(err)
+	defer cl.Close()
+
+	t5, err := cl.BlockTime(5)
+	require.NoError(err)
+
+	info, err := cl.GetBlockInfoAt(5)
+	require.NoError(err)
+	require.Equal(info.Timestamp, t5)
+
+	t6, err := cl.BlockTime(6)
+	require.NoError(err)
+	require.True(t6.After(t5))
+
+	// exactly on the block timestamp
+	height, err := cl.BlockAt(t5)
+	require.NoError(err)
+	require.EqualValues(5, height)
+
+	// between two blocks
+	height, err = cl.BlockAt(t6.Add(-time.Nanosecond))
+	require.NoError(err)
+	require.EqualValues(5, height)
+
+	// after the head
+	height, err = cl.BlockAt(time.Now().Add(time.Hour))
+	require.NoError(err)
+	require.Equal(head, height)
+
+	// before block 1
+	t1, err := cl.BlockTime(1)
+	require.NoError(err)
+	_, err = cl.BlockAt(t1.Add(-time.Millisecond))
+	require.ErrorIs(err, substrate.ErrNotFound)
+
+	// finalized blocks are cached by height, so searching
+	// again needs no block lookups
+	calls := rpcCalls(t, reg, "chain_getBlockHash")
+	height, err = cl.BlockAt(t5)
+	require.NoError(err)
+	require.EqualValues(5, height)
+	require.Equal(calls, rpcCalls(t, reg, "chain_getBlockHash"))
+}
@@ -0,0 +1,372 @@
+package substratetest
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/centrifuge/go-substrate-rpc-client/v4/scale"
+	"github.com/centrifuge/go-substrate-rpc-client/v4/types"
+	"github.com/pkg/errors"
+	substrate "github.com/threefoldtech/substrate-client"
+	"golang.org/x/crypto/blake2b"
+)
+
+const (
+	// slotDuration is the aura slot duration of the chain
+	slotDuration = 6 * time.Second
+)
+
+var (
+	// auraEngineID is the consensus engine id of aura pre-runtime digests
+	auraEngineID = types.ConsensusEngineID(0x61727561)
+
+	// alice is the public key of the //Alice development account which
+	// is the sudo key and block author of the chain
+	alice = types.AccountID{
+		0xd4, 0x35, 0x93, 0xc7, 0x15, 0xfd, 0xd3, 0x1c, 0x61, 0x14, 0x1a, 0xbd, 0x04, 0xa9, 0x9f, 0xd6,
+		0x82, 0x2c, 0x85, 0x58, 0x85, 0x4c, 0xcd, 0xe3, 0x9a, 0x56, 0x84, 0xe7, 0xa5, 0x6d, 0xa2, 0x7d,
+	}
+)
+
+// block is a sealed block and the state after it was executed
+type block struct {
+	hash       types.Hash
+	header     types.Header
+	extrinsics []types.Extrinsic
+	storage    storage
+	time       time.Time
+}
+
+// pending is an extrinsic waiting in the pool for the nonce of its signer
+type pending struct {
+	ext    types.Extrinsic
+	signer types.AccountID
+	nonce  uint64
+	watch  func(types.ExtrinsicStatus)
+}
+
+// Chain is an in-memory chain. Every accepted extrinsic is sealed in its own
+// block which is final right away, empty blocks are produced periodically
+// so the chain time keeps up with the clock.
+type Chain struct {
+	m      sync.Mutex
+	rt     *runtime
+	blocks []*block
+	hashes map[types.Hash]*block
+	// future are extrinsics with a nonce ahead of the signer nonce
+	future []pending
+	// ready are extrinsics waiting for the chain to be resumed
+	ready  []pending
+	paused bool
+	heads  map[int]func(types.Header)
+	nextID int
+}
+
+func newChain() (*Chain, error) {
+	rt, err := newRuntime(
+		systemPallet(),
+		timestampPallet(),
+		auraPallet(),
+		sudoPallet(),
+		utilityPallet(),
+		tfgridPallet(),
+		contractsPallet(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Chain{
+		rt:     rt,
+		hashes: make(map[types.Hash]*block),
+		heads:  make(map[int]func(types.Header)),
+	}
+
+	// genesis has no timestamp like on a real chain
+	genesis := storage{}
+	d := c.context(newOverlay(genesis), 0, time.Time{})
+	d.put([]substrate.AccountID{substrate.AccountID(alice)}, aura, "Authorities")
+	d.put(alice, sudo, "Key")
+	d.st.commit()
+
+	c.append(&block{storage: genesis, header: types.Header{Digest: types.Digest{}}})
+
+	if _, err := c.seal(nil, nil); err != nil {
+		return nil, errors.Wrap(err, "failed to seal first block")
+	}
+
+	return c, nil
+}
+
+func (c *Chain) context(st *overlay, number uint32, now time.Time) *dispatch {
+	return &dispatch{rt: c.rt, st: st, number: number, now: now}
+}
+
+func (c *Chain) append(b *block) {
+	data, err := types.EncodeToBytes(b.header)
+	if err != nil {
+		panic(errors.Wrap(err, "failed to encode header"))
+	}
+
+	b.hash = types.Hash(blake2b.Sum256(data))
+	c.blocks = append(c.blocks, b)
+	c.hashes[b.hash] = b
+}
+
+func (c *Chain) head() *block {
+	return c.blocks[len(c.blocks)-1]
+}
+
+// block returns the block with the given hash, the zero hash is the head
+func (c *Chain) block(hash types.Hash) (*block, error) {
+	if hash == (types.Hash{}) {
+		return c.head(), nil
+	}
+
+	b, ok := c.hashes[hash]
+	if !ok {
+		return nil, fmt.Errorf("block '%s' not found", hash.Hex())
+	}
+
+	return b, nil
+}
+
+// Height returns the number of the last block
+func (c *Chain) Height() uint32 {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	return uint32(c.head().header.Number)
+}
+
+// NewBlock seals an empty block
+func (c *Chain) NewBlock() error {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	_, err := c.seal(nil, nil)
+	return err
+}
+
+// exec seals a block with fn applied as a root call, it's used
+// to seed state that can't be created through extrinsics
+func (c *Chain) exec(fn func(d *dispatch) error) error {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	_, err := c.seal(nil, fn)
+	return err
+}
+
+// SetBalance sets the free balance of the account
+func (c *Chain) SetBalance(account substrate.AccountID, free uint64) error {
+	return c.exec(func(d *dispatch) error {
+		info := d.account(types.AccountID(account))
+		info.Data.Free = types.NewU128(*bigInt(free))
+		d.put(info, system, "Account", types.AccountID(account))
+		return nil
+	})
+}
+
+// SetSudo sets the sudo key of the chain, it's the //Alice account by default
+func (c *Chain) SetSudo(account substrate.AccountID) error {
+	return c.exec(func(d *dispatch) error {
+		d.put(types.AccountID(account), sudo, "Key")
+		return nil
+	})
+}
+
+// subscribe calls fn with the header of every new block until
+// the returned function is called
+func (c *Chain) subscribe(fn func(types.Header)) func() {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	id := c.nextID
+	c.nextID++
+	c.heads[id] = fn
+	fn(c.head().header)
+
+	return func() {
+		c.m.Lock()
+		defer c.m.Unlock()
+
+		delete(c.heads, id)
+	}
+}
+
+// record is an event of the block
+type record struct {
+	phase types.Phase
+	event
+}
+
+// seal executes the extrinsics in a new block on top of the head. init
+// runs as a root call while the block is initialized.
+func (c *Chain) seal(exts []types.Extrinsic, init func(d *dispatch) error) (*block, error) {
+	parent := c.head()
+	number := uint32(parent.header.Number) + 1
+
+	// timestamps are in milliseconds and must increase like on chain
+	now := time.Now().Truncate(time.Millisecond)
+	if !now.After(parent.time) {
+		now = parent.time.Truncate(time.Millisecond).Add(time.Millisecond)
+	}
+
+	st := parent.storage.clone()
+	d := c.context(newOverlay(st), number, now)
+	d.root = true
+	d.put(types.U32(number), system, "Number")
+	if init != nil {
+		if err := init(d); err != nil {
+			return nil, err
+		}
+	}
+	d.st.commit()
+
+	records := make([]record, 0, len(d.events))
+	for _, e := range d.events {
+		records = append(records, record{phase: types.Phase{IsInitialization: true}, event: e})
+	}
+
+	inherent, err := c.timestamp(now)
+	if err != nil {
+		return nil, err
+	}
+
+	exts = append([]types.Extrinsic{inherent}, exts...)
+	for i, ext := range exts {
+		events, err := c.apply(st, ext, number, now)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, e := range events {
+			records = append(records, record{phase: types.Phase{IsApplyExtrinsic: true, AsApplyExtrinsic: uint32(i)}, event: e})
+		}
+	}
+
+	events, err := encodeRecords(records)
+	if err != nil {
+		return nil, err
+	}
+
+	// the events are already encoded so they are stored as they are
+	st[d.key(system, "Events")] = events
+
+	slot, err := types.EncodeToBytes(types.U64(now.UnixMilli() / slotDuration.Milliseconds()))
+	if err != nil {
+		return nil, err
+	}
+
+	extrinsics, err := types.EncodeToBytes(exts)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &block{
+		header: types.Header{
+			ParentHash:     parent.hash,
+			Number:         types.BlockNumber(number),
+			StateRoot:      types.Hash(blake2b.Sum256(append(parent.hash[:], events...))),
+			ExtrinsicsRoot: types.Hash(blake2b.Sum256(extrinsics)),
+			Digest: types.Digest{{
+				IsPreRuntime: true,
+				AsPreRuntime: types.PreRuntime{ConsensusEngineID: auraEngineID, Bytes: slot},
+			}},
+		},
+		extrinsics: exts,
+		storage:    st,
+		time:       now,
+	}
+
+	c.append(b)
+	for _, fn := range c.heads {
+		fn(b.header)
+	}
+
+	return b, nil
+}
+
+// timestamp builds the inherent that sets the time of the block
+func (c *Chain) timestamp(now time.Time) (types.Extrinsic, error) {
+	call, err := types.NewCall(c.rt.meta, "Timestamp.set", types.NewUCompactFromUInt(uint64(now.UnixMilli())))
+	if err != nil {
+		return types.Extrinsic{}, errors.Wrap(err, "failed to create timestamp call")
+	}
+
+	return types.NewExtrinsic(call), nil
+}
+
+// apply executes the extrinsic on st and returns the emitted events. Failed
+// calls are not errors, they only emit ExtrinsicFailed.
+func (c *Chain) apply(st storage, ext types.Extrinsic, number uint32, now time.Time) ([]event, error) {
+	d := c.context(newOverlay(st), number, now)
+	if ext.IsSigned() {
+		signer := ext.Signature.Signer.AsID
+		d.origin = &signer
+
+		// the nonce is consumed even if the call fails
+		info := d.account(signer)
+		info.Nonce++
+		d.put(info, system, "Account", signer)
+		d.st.commit()
+	}
+
+	class := types.DispatchClass{IsNormal: true}
+	if !ext.IsSigned() {
+		class = types.DispatchClass{IsMandatory: true}
+	}
+	dispatchInfo := types.DispatchInfo{Class: class, PaysFee: types.Pays{IsYes: ext.IsSigned(), IsNo: !ext.IsSigned()}}
+
+	if err := c.rt.dispatch(d, ext.Method); err != nil {
+		var perr *palletError
+		if !errors.As(err, &perr) && !errors.Is(err, errBadOrigin) {
+			return nil, err
+		}
+
+		d.events = nil
+		d.emit(system, "ExtrinsicFailed", c.rt.dispatchError(err), dispatchInfo)
+		return d.events, nil
+	}
+
+	d.st.commit()
+	d.emit(system, "ExtrinsicSuccess", dispatchInfo)
+	return d.events, nil
+}
+
+func encodeRecords(records []record) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := scale.NewEncoder(&buf)
+	if err := encoder.EncodeUintCompact(*bigInt(uint64(len(records)))); err != nil {
+		return nil, err
+	}
+
+	for _, r := range records {
+		if err := encoder.Encode(r.phase); err != nil {
+			return nil, err
+		}
+
+		if err := encoder.Encode(r.id); err != nil {
+			return nil, err
+		}
+
+		if err := encoder.Write(r.data); err != nil {
+			return nil, err
+		}
+
+		// no topics
+		if err := encoder.Encode([]types.Hash{}); err != nil {
+			return nil, err
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+func bigInt(v uint64) *big.Int {
+	return new(big.Int).SetUint64(v)
+}
@@ -0,0 +1,372 @@
+package substratetest
+
+import (
+	"github.com/centrifuge/go-substrate-rpc-client/v4/types"
+	substrate "github.com/threefoldtech/substrate-client"
+)
+
+const (
+	contracts = "SmartContractModule"
+
+	contractVersion = 3
+)
+
+func contractsPallet() pallet {
+	return pallet{
+		name:  contracts,
+		index: 12,
+		storage: []entry{
+			mapped("Contracts", substrate.Contract{}, types.U64(0)),
+			plain("ContractID", types.U64(0)),
+			mapped("ContractIDByNodeIDAndHash", types.U64(0), types.U32(0), ""),
+			mapped("ContractIDByNameRegistration", types.U64(0), ""),
+			mapped("ActiveRentContractForNode", types.U64(0), types.U32(0)),
+			mapped("ActiveNodeContracts", []types.U64{}, types.U32(0)),
+		},
+		calls: []call{
+			{name: "create_node_contract", fn: createNodeContract},
+			{name: "update_node_contract", fn: updateNodeContract},
+			{name: "cancel_contract", fn: cancelContract},
+			{name: "create_name_contract", fn: createNameContract},
+			{name: "create_rent_contract", fn: createRentContract},
+			{name: "add_nru_reports", fn: addNruReports},
+			{name: "report_contract_resources", fn: reportContractResources},
+		},
+		errors: []string{
+			"TwinNotExists",
+			"NodeNotExists",
+			"FarmNotExists",
+			"FarmHasNotEnoughPublicIPs",
+			"FarmHasNotEnoughPublicIPsFree",
+			"ContractNotExists",
+			"ContractIsNotUnique",
+			"NameExists",
+			"NodeHasActiveContracts",
+			"NodeHasRentContract",
+			"NodeNotAvailableToDeploy",
+			"TwinNotAuthorizedToUpdateContract",
+			"TwinNotAuthorizedToCancelContract",
+		},
+	}
+}
+
+// contractTwin returns the twin of the signer, unlike twinOf
+// it fails with the contracts pallet errors
+func (d *dispatch) contractTwin() (substrate.Twin, error) {
+	signer, err := d.signer()
+	if err != nil {
+		return substrate.Twin{}, err
+	}
+
+	twin, err := d.twinOf(signer)
+	if err != nil {
+		return twin, d.fail(contracts, "TwinNotExists")
+	}
+
+	return twin, nil
+}
+
+// newContract builds a new contract of the twin with the next contract id
+func (d *dispatch) newContract(twin types.U32, typ substrate.ContractType) substrate.Contract {
+	var id types.U64
+	d.get(&id, contracts, "ContractID")
+	id++
+	d.put(id, contracts, "ContractID")
+
+	contract := substrate.Contract{
+		Versioned:    substrate.Versioned{Version: contractVersion},
+		State:        substrate.ContractState{IsCreated: true},
+		ContractID:   id,
+		TwinID:       twin,
+		ContractType: typ,
+	}
+
+	return contract
+}
+
+// storeContract stores a newly created contract
+func (d *dispatch) storeContract(contract substrate.Contract) {
+	d.put(contract, contracts, "Contracts", contract.ContractID)
+	d.emit(contracts, "ContractCreated", contract)
+}
+
+// ownedContract returns the contract if it's owned by the twin
+func (d *dispatch) ownedContract(id types.U64, twin types.U32, unauthorized string) (substrate.Contract, error) {
+	var contract substrate.Contract
+	if !d.get(&contract, contracts, "Contracts", id) {
+		return contract, d.fail(contracts, "ContractNotExists")
+	}
+
+	if contract.TwinID != twin {
+		return contract, d.fail(contracts, unauthorized)
+	}
+
+	return contract, nil
+}
+
+type createNodeContractArgs struct {
+	NodeID         types.U32
+	DeploymentData []byte
+	DeploymentHash string
+	PublicIPs      types.U32
+}
+
+func createNodeContract(d *dispatch, args createNodeContractArgs) error {
+	twin, err := d.contractTwin()
+	if err != nil {
+		return err
+	}
+
+	var node substrate.Node
+	if !d.get(&node, tfgrid, "Nodes", args.NodeID) {
+		return d.fail(contracts, "NodeNotExists")
+	}
+
+	var rent types.U64
+	if d.get(&rent, contracts, "ActiveRentContractForNode", node.ID) {
+		var contract substrate.Contract
+		if d.get(&contract, contracts, "Contracts", rent) && contract.TwinID != twin.ID {
+			return d.fail(contracts, "NodeNotAvailableToDeploy")
+		}
+	}
+
+	if d.get(new(types.U64), contracts, "ContractIDByNodeIDAndHash", node.ID, args.DeploymentHash) {
+		return d.fail(contracts, "ContractIsNotUnique")
+	}
+
+	var farm substrate.Farm
+	if !d.get(&farm, tfgrid, "Farms", node.FarmID) {
+		return d.fail(contracts, "FarmNotExists")
+	}
+
+	if int(args.PublicIPs) > len(farm.PublicIPs) {
+		return d.fail(contracts, "FarmHasNotEnoughPublicIPs")
+	}
+
+	var free []int
+	for i, ip := range farm.PublicIPs {
+		if ip.ContractID == 0 && len(free) < int(args.PublicIPs) {
+			free = append(free, i)
+		}
+	}
+
+	if len(free) < int(args.PublicIPs) {
+		return d.fail(contracts, "FarmHasNotEnoughPublicIPsFree")
+	}
+
+	contract := d.newContract(twin.ID, substrate.ContractType{
+		IsNodeContract: true,
+		NodeContract: substrate.NodeContract{
+			Node:           node.ID,
+			DeploymentData: args.DeploymentData,
+			DeploymentHash: args.DeploymentHash,
+			PublicIPsCount: args.PublicIPs,
+		},
+	})
+
+	if len(free) > 0 {
+		for _, i := range free {
+			farm.PublicIPs[i].ContractID = contract.ContractID
+			contract.ContractType.NodeContract.PublicIPs = append(contract.ContractType.NodeContract.PublicIPs, farm.PublicIPs[i])
+		}
+
+		d.put(farm, tfgrid, "Farms", farm.ID)
+	}
+
+	d.storeContract(contract)
+
+	var active []types.U64
+	d.get(&active, contracts, "ActiveNodeContracts", node.ID)
+	d.put(append(active, contract.ContractID), contracts, "ActiveNodeContracts", node.ID)
+	d.put(contract.ContractID, contracts, "ContractIDByNodeIDAndHash", node.ID, args.DeploymentHash)
+	return nil
+}
+
+type updateNodeContractArgs struct {
+	ContractID     types.U64
+	DeploymentData []byte
+	DeploymentHash string
+}
+
+func updateNodeContract(d *dispatch, args updateNodeContractArgs) error {
+	twin, err := d.contractTwin()
+	if err != nil {
+		return err
+	}
+
+	contract, err := d.ownedContract(args.ContractID, twin.ID, "TwinNotAuthorizedToUpdateContract")
+	if err != nil {
+		return err
+	}
+
+	if !contract.ContractType.IsNodeContract {
+		return d.fail(contracts, "ContractNotExists")
+	}
+
+	nc := &contract.ContractType.NodeContract
+	if nc.DeploymentHash != args.DeploymentHash {
+		if d.get(new(types.U64), contracts, "ContractIDByNodeIDAndHash", nc.Node, args.DeploymentHash) {
+			return d.fail(contracts, "ContractIsNotUnique")
+		}
+
+		d.remove(contracts, "ContractIDByNodeIDAndHash", nc.Node, nc.DeploymentHash)
+		d.put(contract.ContractID, contracts, "ContractIDByNodeIDAndHash", nc.Node, args.DeploymentHash)
+	}
+
+	nc.DeploymentData = args.DeploymentData
+	nc.DeploymentHash = args.DeploymentHash
+
+	d.put(contract, contracts, "Contracts", contract.ContractID)
+	d.emit(contracts, "ContractUpdated", contract)
+	return nil
+}
+
+type cancelContractArgs struct {
+	ContractID types.U64
+}
+
+// cancelContract removes the contract right away, the
+// real chain keeps it until it's billed for the last time
+func cancelContract(d *dispatch, args cancelContractArgs) error {
+	twin, err := d.contractTwin()
+	if err != nil {
+		return err
+	}
+
+	contract, err := d.ownedContract(args.ContractID, twin.ID, "TwinNotAuthorizedToCancelContract")
+	if err != nil {
+		return err
+	}
+
+	typ := contract.ContractType
+	switch {
+	case typ.IsNodeContract:
+		nc := typ.NodeContract
+		if len(nc.PublicIPs) > 0 {
+			var farm substrate.Farm
+			var node substrate.Node
+			if d.get(&node, tfgrid, "Nodes", nc.Node) && d.get(&farm, tfgrid, "Farms", node.FarmID) {
+				for i := range farm.PublicIPs {
+					if farm.PublicIPs[i].ContractID == contract.ContractID {
+						farm.PublicIPs[i].ContractID = 0
+					}
+				}
+				d.put(farm, tfgrid, "Farms", farm.ID)
+			}
+		}
+
+		var active, remaining []types.U64
+		d.get(&active, contracts, "ActiveNodeContracts", nc.Node)
+		for _, id := range active {
+			if id != contract.ContractID {
+				remaining = append(remaining, id)
+			}
+		}
+		d.put(remaining, contracts, "ActiveNodeContracts", nc.Node)
+		d.remove(contracts, "ContractIDByNodeIDAndHash", nc.Node, nc.DeploymentHash)
+		d.emit(contracts, "NodeContractCanceled", contract.ContractID, nc.Node, contract.TwinID)
+	case typ.IsNameContract:
+		d.remove(contracts, "ContractIDByNameRegistration", typ.NameContract.Name)
+		d.emit(contracts, "NameContractCanceled", contract.ContractID)
+	case typ.IsRentContract:
+		var active []types.U64
+		if d.get(&active, contracts, "ActiveNodeContracts", typ.RentContract.Node) && len(active) > 0 {
+			return d.fail(contracts, "NodeHasActiveContracts")
+		}
+
+		d.remove(contracts, "ActiveRentContractForNode", typ.RentContract.Node)
+		d.emit(contracts, "RentContractCanceled", contract.ContractID)
+	}
+
+	d.remove(contracts, "Contracts", contract.ContractID)
+	return nil
+}
+
+type createNameContractArgs struct {
+	Name string
+}
+
+func createNameContract(d *dispatch, args createNameContractArgs) error {
+	twin, err := d.contractTwin()
+	if err != nil {
+		return err
+	}
+
+	if d.get(new(types.U64), contracts, "ContractIDByNameRegistration", args.Name) {
+		return d.fail(contracts, "NameExists")
+	}
+
+	contract := d.newContract(twin.ID, substrate.ContractType{
+		IsNameContract: true,
+		NameContract:   substrate.NameContract{Name: args.Name},
+	})
+
+	d.storeContract(contract)
+	d.put(contract.ContractID, contracts, "ContractIDByNameRegistration", args.Name)
+	return nil
+}
+
+type createRentContractArgs struct {
+	NodeID types.U32
+}
+
+func createRentContract(d *dispatch, args createRentContractArgs) error {
+	twin, err := d.contractTwin()
+	if err != nil {
+		return err
+	}
+
+	if !d.get(&substrate.Node{}, tfgrid, "Nodes", args.NodeID) {
+		return d.fail(contracts, "NodeNotExists")
+	}
+
+	if d.get(new(types.U64), contracts, "ActiveRentContractForNode", args.NodeID) {
+		return d.fail(contracts, "NodeHasRentContract")
+	}
+
+	var active []types.U64
+	if d.get(&active, contracts, "ActiveNodeContracts", args.NodeID) && len(active) > 0 {
+		return d.fail(contracts, "NodeHasActiveContracts")
+	}
+
+	contract := d.newContract(twin.ID, substrate.ContractType{
+		IsRentContract: true,
+		RentContract:   substrate.RentContract{Node: args.NodeID},
+	})
+
+	d.storeContract(contract)
+	d.put(contract.ContractID, contracts, "ActiveRentContractForNode", args.NodeID)
+	return nil
+}
+
+type addNruReportsArgs struct {
+	Reports []substrate.NruConsumption
+}
+
+func addNruReports(d *dispatch, args addNruReportsArgs) error {
+	if _, err := d.signer(); err != nil {
+		return err
+	}
+
+	for _, report := range args.Reports {
+		d.emit(contracts, "NruConsumptionReportReceived", report)
+	}
+
+	return nil
+}
+
+type reportContractResourcesArgs struct {
+	Resources []substrate.ContractResources
+}
+
+func reportContractResources(d *dispatch, args reportContractResourcesArgs) error {
+	if _, err := d.signer(); err != nil {
+		return err
+	}
+
+	for _, resources := range args.Resources {
+		d.emit(contracts, "UpdatedUsedResources", resources)
+	}
+
+	return nil
+}
@@ -0,0 +1,354 @@
+package substratetest
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/centrifuge/go-substrate-rpc-client/v4/scale"
+	"github.com/centrifuge/go-substrate-rpc-client/v4/types"
+	"github.com/pkg/errors"
+)
+
+var (
+	// errBadOrigin is returned by calls dispatched with the wrong origin
+	errBadOrigin = fmt.Errorf("bad origin")
+)
+
+// palletError is a module error raised by a call
+type palletError struct {
+	pallet string
+	name   string
+}
+
+func (e *palletError) Error() string {
+	return fmt.Sprintf("%s.%s", e.pallet, e.name)
+}
+
+// store is a key value storage
+type store interface {
+	get(key string) ([]byte, bool)
+	set(key string, value []byte)
+}
+
+// storage is the state of the chain at a block
+type storage map[string][]byte
+
+func (s storage) get(key string) ([]byte, bool) {
+	value, ok := s[key]
+	return value, ok
+}
+
+func (s storage) set(key string, value []byte) {
+	if value == nil {
+		delete(s, key)
+		return
+	}
+
+	s[key] = value
+}
+
+func (s storage) clone() storage {
+	cp := make(storage, len(s))
+	for key, value := range s {
+		cp[key] = value
+	}
+
+	return cp
+}
+
+// overlay keeps changes on top of a store until they are committed,
+// so the changes of failed calls can be discarded
+type overlay struct {
+	parent  store
+	changes map[string][]byte
+}
+
+func newOverlay(parent store) *overlay {
+	return &overlay{parent: parent, changes: make(map[string][]byte)}
+}
+
+func (o *overlay) get(key string) ([]byte, bool) {
+	if value, ok := o.changes[key]; ok {
+		return value, value != nil
+	}
+
+	return o.parent.get(key)
+}
+
+// set stores the value, a nil value removes the key
+func (o *overlay) set(key string, value []byte) {
+	o.changes[key] = value
+}
+
+func (o *overlay) commit() {
+	for key, value := range o.changes {
+		o.parent.set(key, value)
+	}
+	o.changes = make(map[string][]byte)
+}
+
+// event is an event emitted by a call
+type event struct {
+	id   types.EventID
+	data []byte
+}
+
+// dispatch is the context calls are executed in. Storage helpers panic on
+// encoding errors since those can only be caused by a bug in the runtime.
+type dispatch struct {
+	rt *runtime
+	st *overlay
+	// origin is the signer of the extrinsic, it's nil for
+	// unsigned extrinsics and root calls
+	origin *types.AccountID
+	root   bool
+	number uint32
+	now    time.Time
+	events []event
+}
+
+// signer returns the account that signed the extrinsic
+func (d *dispatch) signer() (types.AccountID, error) {
+	if d.origin == nil {
+		return types.AccountID{}, errBadOrigin
+	}
+
+	return *d.origin, nil
+}
+
+// nested returns a dispatch context whose changes are only
+// applied to d when committed
+func (d *dispatch) nested() *dispatch {
+	cp := *d
+	cp.st = newOverlay(d.st)
+	cp.events = nil
+	return &cp
+}
+
+// commit applies the changes and events of a nested context to its parent
+func (d *dispatch) commit(parent *dispatch) {
+	d.st.commit()
+	parent.events = append(parent.events, d.events...)
+}
+
+// fail returns the pallet error with the given name
+func (d *dispatch) fail(pallet, name string) error {
+	return &palletError{pallet: pallet, name: name}
+}
+
+func (d *dispatch) key(pallet, item string, keys ...interface{}) string {
+	args := make([][]byte, 0, len(keys))
+	for _, key := range keys {
+		data, err := types.EncodeToBytes(key)
+		if err != nil {
+			panic(errors.Wrapf(err, "failed to encode key of %s.%s", pallet, item))
+		}
+		args = append(args, data)
+	}
+
+	key, err := types.CreateStorageKey(d.rt.meta, pallet, item, args...)
+	if err != nil {
+		panic(errors.Wrapf(err, "failed to create key of %s.%s", pallet, item))
+	}
+
+	return string(key)
+}
+
+// get loads the value of the storage item into value, it returns false
+// if the item is not set
+func (d *dispatch) get(value interface{}, pallet, item string, keys ...interface{}) bool {
+	data, ok := d.st.get(d.key(pallet, item, keys...))
+	if !ok {
+		return false
+	}
+
+	if err := types.DecodeFromBytes(data, value); err != nil {
+		panic(errors.Wrapf(err, "failed to decode %s.%s", pallet, item))
+	}
+
+	return true
+}
+
+// put sets the value of the storage item
+func (d *dispatch) put(value interface{}, pallet, item string, keys ...interface{}) {
+	data, err := types.EncodeToBytes(value)
+	if err != nil {
+		panic(errors.Wrapf(err, "failed to encode %s.%s", pallet, item))
+	}
+
+	d.st.set(d.key(pallet, item, keys...), data)
+}
+
+// remove deletes the storage item
+func (d *dispatch) remove(pallet, item string, keys ...interface{}) {
+	d.st.set(d.key(pallet, item, keys...), nil)
+}
+
+// emit deposits an event, fields are encoded in the given order
+func (d *dispatch) emit(pallet, name string, fields ...interface{}) {
+	id, err := d.rt.event(pallet, name)
+	if err != nil {
+		panic(err)
+	}
+
+	var buf bytes.Buffer
+	encoder := scale.NewEncoder(&buf)
+	for _, field := range fields {
+		if err := encoder.Encode(field); err != nil {
+			panic(errors.Wrapf(err, "failed to encode event %s.%s", pallet, name))
+		}
+	}
+
+	d.events = append(d.events, event{id: id, data: buf.Bytes()})
+}
+
+// decode decodes the arguments of the call
+func (rt *runtime) decode(call types.Call) (handler, reflect.Value, error) {
+	h, ok := rt.handlers[call.CallIndex]
+	if !ok {
+		return h, reflect.Value{}, fmt.Errorf("call %d.%d not found", call.CallIndex.SectionIndex, call.CallIndex.MethodIndex)
+	}
+
+	args := reflect.New(h.args)
+	reader := bytes.NewReader(call.Args)
+	if err := scale.NewDecoder(reader).Decode(args.Interface()); err != nil {
+		return h, args, errors.Wrapf(err, "failed to decode arguments of %s.%s", h.pallet, h.name)
+	}
+
+	if reader.Len() != 0 {
+		return h, args, fmt.Errorf("%d bytes left after decoding arguments of %s.%s", reader.Len(), h.pallet, h.name)
+	}
+
+	return h, args.Elem(), nil
+}
+
+// batchCalls is an encoded vector of calls. The size of a call depends on
+// its arguments, so the calls are split by the runtime that knows them.
+type batchCalls []byte
+
+// Decode implementation, it reads all the remaining bytes
+func (c *batchCalls) Decode(decoder scale.Decoder) error {
+	var raw types.Args
+	if err := decoder.Decode(&raw); err != nil {
+		return err
+	}
+
+	*c = batchCalls(raw)
+	return nil
+}
+
+// split decodes the calls of a batch
+func (rt *runtime) split(raw batchCalls) ([]types.Call, error) {
+	reader := bytes.NewReader(raw)
+	decoder := scale.NewDecoder(reader)
+
+	count, err := decoder.DecodeUintCompact()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode number of calls")
+	}
+
+	calls := make([]types.Call, 0, count.Uint64())
+	for i := uint64(0); i < count.Uint64(); i++ {
+		var index types.CallIndex
+		if err := decoder.Decode(&index); err != nil {
+			return nil, errors.Wrapf(err, "failed to decode index of call %d", i)
+		}
+
+		h, ok := rt.handlers[index]
+		if !ok {
+			return nil, fmt.Errorf("call %d.%d not found", index.SectionIndex, index.MethodIndex)
+		}
+
+		start := len(raw) - reader.Len()
+		if err := decoder.Decode(reflect.New(h.args).Interface()); err != nil {
+			return nil, errors.Wrapf(err, "failed to decode arguments of %s.%s", h.pallet, h.name)
+		}
+
+		end := len(raw) - reader.Len()
+		calls = append(calls, types.Call{CallIndex: index, Args: types.Args(raw[start:end])})
+	}
+
+	if reader.Len() != 0 {
+		return nil, fmt.Errorf("%d bytes left after decoding calls", reader.Len())
+	}
+
+	return calls, nil
+}
+
+// dispatch executes the call, changes are only applied to d if
+// the call succeeds
+func (rt *runtime) dispatch(d *dispatch, call types.Call) error {
+	h, args, err := rt.decode(call)
+	if err != nil {
+		return err
+	}
+
+	nested := d.nested()
+	out := h.fn.Call([]reflect.Value{reflect.ValueOf(nested), args})
+	if err, _ := out[0].Interface().(error); err != nil {
+		return err
+	}
+
+	nested.commit(d)
+	return nil
+}
+
+// dispatchError is a DispatchError as encoded by substrate. Unlike
+// types.DispatchError it can encode errors other than module errors.
+type dispatchError struct {
+	variant byte
+	module  moduleError
+}
+
+// Encode implementation
+func (e dispatchError) Encode(encoder scale.Encoder) error {
+	if err := encoder.PushByte(e.variant); err != nil {
+		return err
+	}
+
+	if e.variant == 3 {
+		return encoder.Encode(e.module)
+	}
+
+	return nil
+}
+
+// dispatchError converts the error returned by a call
+func (rt *runtime) dispatchError(err error) dispatchError {
+	var perr *palletError
+	if errors.As(err, &perr) {
+		module, err := rt.moduleError(perr.pallet, perr.name)
+		if err != nil {
+			panic(err)
+		}
+
+		return dispatchError{variant: 3, module: moduleError{Index: types.U8(module.Module), Error: types.U8(module.Error)}}
+	}
+
+	if errors.Is(err, errBadOrigin) {
+		return dispatchError{variant: 2}
+	}
+
+	// Other
+	return dispatchError{}
+}
+
+// dispatchResult is the result of a call dispatched by another call
+type dispatchResult struct {
+	err *dispatchError
+}
+
+// Encode implementation
+func (r dispatchResult) Encode(encoder scale.Encoder) error {
+	if r.err == nil {
+		return encoder.PushByte(0)
+	}
+
+	if err := encoder.PushByte(1); err != nil {
+		return err
+	}
+
+	return encoder.Encode(*r.err)
+}
@@ -0,0 +1,333 @@
+package substratetest
+
+import (
+	"crypto/ed25519"
+	"fmt"
+	"math/big"
+
+	"github.com/ChainSafe/go-schnorrkel"
+	"github.com/centrifuge/go-substrate-rpc-client/v4/types"
+	"golang.org/x/crypto/blake2b"
+)
+
+// invalidTransaction is an InvalidTransaction error of the transaction
+// pool, the value is the index of the error variant
+type invalidTransaction uint8
+
+const (
+	invalidCall              invalidTransaction = 0
+	invalidFuture            invalidTransaction = 2
+	invalidStale             invalidTransaction = 3
+	invalidBadProof          invalidTransaction = 4
+	invalidAncientBirthBlock invalidTransaction = 5
+	invalidBadSigner         invalidTransaction = 10
+)
+
+func (e invalidTransaction) Error() string {
+	switch e {
+	case invalidCall:
+		return "Transaction call is not expected"
+	case invalidFuture:
+		return "Transaction will be valid in the future"
+	case invalidStale:
+		return "Transaction is outdated"
+	case invalidBadProof:
+		return "Transaction has a bad signature"
+	case invalidAncientBirthBlock:
+		return "Transaction has an ancient birth block"
+	case invalidBadSigner:
+		return "Transaction has a bad signer"
+	}
+
+	return fmt.Sprintf("Invalid transaction %d", uint8(e))
+}
+
+// validate checks the extrinsic against the head of the chain and returns
+// its signer and nonce. An extrinsic with a nonce ahead of the signer is
+// valid, it's up to the caller to keep it until it can be applied.
+func (c *Chain) validate(ext types.Extrinsic) (types.AccountID, uint64, error) {
+	var signer types.AccountID
+	if ext.Type() != types.ExtrinsicVersion4 || !ext.IsSigned() {
+		return signer, 0, invalidCall
+	}
+
+	if _, _, err := c.rt.decode(ext.Method); err != nil {
+		return signer, 0, invalidCall
+	}
+
+	sig := ext.Signature
+	if !sig.Signer.IsID {
+		return signer, 0, invalidBadSigner
+	}
+	signer = sig.Signer.AsID
+
+	checkpoint, err := c.checkpoint(sig.Era)
+	if err != nil {
+		return signer, 0, err
+	}
+
+	method, err := types.EncodeToBytes(ext.Method)
+	if err != nil {
+		return signer, 0, invalidCall
+	}
+
+	payload, err := types.EncodeToBytes(types.ExtrinsicPayloadV4{
+		ExtrinsicPayloadV3: types.ExtrinsicPayloadV3{
+			Method:      method,
+			Era:         sig.Era,
+			Nonce:       sig.Nonce,
+			Tip:         sig.Tip,
+			SpecVersion: specVersion,
+			GenesisHash: c.blocks[0].hash,
+			BlockHash:   checkpoint,
+		},
+		TransactionVersion: transactionVersion,
+	})
+	if err != nil {
+		return signer, 0, invalidCall
+	}
+
+	if !verify(signer, sig.Signature, payload) {
+		return signer, 0, invalidBadProof
+	}
+
+	n := big.Int(sig.Nonce)
+	nonce := n.Uint64()
+	if nonce < c.nonceOf(signer) {
+		return signer, nonce, invalidStale
+	}
+
+	return signer, nonce, nil
+}
+
+// checkpoint returns the block hash the signature of an extrinsic with the
+// given era commits to. This follows `Era::birth` in substrate.
+func (c *Chain) checkpoint(era types.ExtrinsicEra) (types.Hash, error) {
+	if !era.IsMortalEra {
+		return c.blocks[0].hash, nil
+	}
+
+	encoded := uint64(era.AsMortalEra.First) | uint64(era.AsMortalEra.Second)<<8
+	period := uint64(2) << (encoded % (1 << 4))
+	quantize := period >> 12
+	if quantize < 1 {
+		quantize = 1
+	}
+	phase := (encoded >> 4) * quantize
+
+	current := uint64(c.head().header.Number)
+	if current < phase {
+		return types.Hash{}, invalidAncientBirthBlock
+	}
+
+	birth := (current-phase)/period*period + phase
+	if birth+period <= current || birth >= uint64(len(c.blocks)) {
+		return types.Hash{}, invalidAncientBirthBlock
+	}
+
+	return c.blocks[birth].hash, nil
+}
+
+// verify checks the ed25519 or sr25519 signature of the payload
+func verify(signer types.AccountID, sig types.MultiSignature, payload []byte) bool {
+	if len(payload) > 256 {
+		h := blake2b.Sum256(payload)
+		payload = h[:]
+	}
+
+	switch {
+	case sig.IsEd25519:
+		return ed25519.Verify(signer[:], payload, sig.AsEd25519[:])
+	case sig.IsSr25519:
+		pk, err := schnorrkel.NewPublicKey([32]byte(signer))
+		if err != nil {
+			return false
+		}
+
+		var s schnorrkel.Signature
+		if err := s.Decode([64]byte(sig.AsSr25519)); err != nil {
+			return false
+		}
+
+		ok, err := pk.Verify(&s, schnorrkel.NewSigningContext([]byte("substrate"), payload))
+		return err == nil && ok
+	}
+
+	return false
+}
+
+// nonceOf returns the next nonce of the account at the head of the chain
+func (c *Chain) nonceOf(account types.AccountID) uint64 {
+	head := c.head()
+
+	var info types.AccountInfo
+	c.context(newOverlay(head.storage), 0, head.time).get(&info, system, "Account", account)
+	return uint64(info.Nonce)
+}
+
+// poolNonce returns the next nonce of the account including
+// the extrinsics waiting in the pool for the chain to be resumed
+func (c *Chain) poolNonce(account types.AccountID) uint64 {
+	nonce := c.nonceOf(account)
+	for _, p := range c.ready {
+		if p.signer == account {
+			nonce++
+		}
+	}
+
+	return nonce
+}
+
+// submit validates the extrinsic and seals it in a new block, watch is called
+// with the status updates of the extrinsic. Extrinsics with a nonce ahead of
+// the signer nonce are kept until the missing extrinsics are submitted.
+func (c *Chain) submit(ext types.Extrinsic, watch func(types.ExtrinsicStatus)) error {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	signer, nonce, err := c.validate(ext)
+	if err != nil {
+		return err
+	}
+
+	if nonce > c.poolNonce(signer) {
+		c.future = append(c.future, pending{ext: ext, signer: signer, nonce: nonce, watch: watch})
+		watch(types.ExtrinsicStatus{IsFuture: true})
+		return nil
+	}
+
+	ready := []pending{{ext: ext, signer: signer, nonce: nonce, watch: watch}}
+	for {
+		next, ok := c.promote(signer, nonce+uint64(len(ready)))
+		if !ok {
+			break
+		}
+		ready = append(ready, next)
+	}
+
+	for _, p := range ready {
+		p.watch(types.ExtrinsicStatus{IsReady: true})
+	}
+
+	if c.paused {
+		c.ready = append(c.ready, ready...)
+		return nil
+	}
+
+	return c.include(ready)
+}
+
+// include seals the ready extrinsics in a new block
+func (c *Chain) include(ready []pending) error {
+	exts := make([]types.Extrinsic, 0, len(ready))
+	for _, p := range ready {
+		exts = append(exts, p.ext)
+	}
+
+	b, err := c.seal(exts, nil)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range ready {
+		p.watch(types.ExtrinsicStatus{IsInBlock: true, AsInBlock: b.hash})
+		p.watch(types.ExtrinsicStatus{IsFinalized: true, AsFinalized: b.hash})
+	}
+
+	return nil
+}
+
+// Pause keeps the submitted extrinsics in the pool instead of sealing
+// them, until Resume is called
+func (c *Chain) Pause() {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	c.paused = true
+}
+
+// Resume seals the extrinsics kept in the pool while the chain was paused
+func (c *Chain) Resume() error {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	c.paused = false
+	if len(c.ready) == 0 {
+		return nil
+	}
+
+	ready := c.ready
+	c.ready = nil
+	return c.include(ready)
+}
+
+// DropPool drops all the extrinsics in the pool without notifying their
+// watchers, like a node that restarted
+func (c *Chain) DropPool() {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	c.ready = nil
+	c.future = nil
+}
+
+// promote removes the future extrinsic of the signer with the given nonce
+func (c *Chain) promote(signer types.AccountID, nonce uint64) (pending, bool) {
+	for i, p := range c.future {
+		if p.signer == signer && p.nonce == nonce {
+			c.future = append(c.future[:i], c.future[i+1:]...)
+			return p, true
+		}
+	}
+
+	return pending{}, false
+}
+
+// dryRun applies the extrinsic on top of the head without sealing a block
+// and returns the encoded ApplyExtrinsicResult
+func (c *Chain) dryRun(ext types.Extrinsic) ([]byte, error) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	signer, nonce, err := c.validate(ext)
+	if err == nil && nonce > c.nonceOf(signer) {
+		err = invalidFuture
+	}
+
+	if invalid, ok := err.(invalidTransaction); ok {
+		// Err(Invalid(..))
+		return []byte{1, 0, byte(invalid)}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	head := c.head()
+	events, err := c.apply(head.storage.clone(), ext, uint32(head.header.Number)+1, head.time)
+	if err != nil {
+		return nil, err
+	}
+
+	failed, err := c.rt.event(system, "ExtrinsicFailed")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, e := range events {
+		if e.id != failed {
+			continue
+		}
+
+		// the event data starts with the dispatch error, only
+		// module errors have more than the variant byte
+		size := 1
+		if e.data[0] == 3 {
+			size = 3
+		}
+
+		// Ok(Err(DispatchError))
+		return append([]byte{0, 1}, e.data[:size]...), nil
+	}
+
+	// Ok(Ok(()))
+	return []byte{0, 0}, nil
+}
@@ -0,0 +1,120 @@
+package substratetest
+
+import (
+	"net"
+	"testing"
+
+	"github.com/centrifuge/go-substrate-rpc-client/v4/types"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	substrate "github.com/threefoldtech/substrate-client"
+)
+
+func TestEstimateFee(t *testing.T) {
+	require := require.New(t)
+
+	srv, err := NewServer()
+	require.NoError(err)
+	defer srv.Close()
+
+	mgr := substrate.NewManager(srv.URL())
+	defer mgr.Close()
+
+	cl, err := mgr.Substrate()
+	require.NoError(err)
+	defer cl.Close()
+
+	user, err := substrate.NewIdentityFromSr25519Phrase("//Bob")
+	require.NoError(err)
+
+	_, meta, err := cl.GetClient()
+	require.NoError(err)
+
+	accept, err := types.NewCall(meta, "TfgridModule.user_accept_tc", "link", "hash")
+	require.NoError(err)
+
+	fee, err := cl.EstimateFee(user, accept)
+	require.NoError(err)
+	require.Equal(1, fee.Sign())
+
+	// the fee grows with the size of the extrinsic
+	longer, err := types.NewCall(meta, "TfgridModule.user_accept_tc", "a much longer link", "hash")
+	require.NoError(err)
+
+	longerFee, err := cl.EstimateFee(user, longer)
+	require.NoError(err)
+	require.Equal(int64(byteFee*len("a much longer link")-byteFee*len("link")), longerFee.Int64()-fee.Int64())
+
+	// failed calls pay fees too
+	create, err := types.NewCall(meta, "TfgridModule.create_twin", "::1")
+	require.NoError(err)
+	require.Error(cl.DryRun(user, create))
+
+	fee, err = cl.EstimateFee(user, create)
+	require.NoError(err)
+	require.Equal(1, fee.Sign())
+
+	// like substrate the extrinsic is not validated
+	for i := 0; i < 10; i++ {
+		require.NoError(srv.NewBlock())
+	}
+
+	require.ErrorIs(cl.DryRun(user, accept, substrate.WithMortalEraAt(4, 1)), substrate.ErrInvalidTransaction)
+
+	fee, err = cl.EstimateFee(user, accept, substrate.WithMortalEraAt(4, 1))
+	require.NoError(err)
+	require.Equal(1, fee.Sign())
+}
+
+func TestDryRun(t *testing.T) {
+	require := require.New(t)
+
+	srv, err := NewServer()
+	require.NoError(err)
+	defer srv.Close()
+
+	mgr := substrate.NewManager(srv.URL())
+	defer mgr.Close()
+
+	cl, err := mgr.Substrate()
+	require.NoError(err)
+	defer cl.Close()
+
+	user, err := substrate.NewIdentityFromSr25519Phrase("//Bob")
+	require.NoError(err)
+
+	_, meta, err := cl.GetClient()
+	require.NoError(err)
+
+	accept, err := types.NewCall(meta, "TfgridModule.user_accept_tc", "link", "hash")
+	require.NoError(err)
+
+	create, err := types.NewCall(meta, "TfgridModule.create_twin", "::1")
+	require.NoError(err)
+
+	require.NoError(cl.DryRun(user, accept))
+
+	// the dry run didn't change the state
+	err = cl.DryRun(user, create)
+	var de *substrate.DispatchError
+	require.True(errors.As(err, &de))
+	require.Equal("TfgridModule", de.Pallet)
+	require.Equal("UserDidNotSignTermsAndConditions", de.Name)
+
+	_, err = cl.CreateTwin(user, net.ParseIP("::1"))
+	require.True(errors.As(err, &de))
+	require.Equal("UserDidNotSignTermsAndConditions", de.Name)
+
+	// the era of the extrinsic is over, so the chain checks the signature
+	// against another checkpoint block like substrate does
+	for i := 0; i < 10; i++ {
+		require.NoError(srv.NewBlock())
+	}
+
+	err = cl.DryRun(user, accept, substrate.WithMortalEraAt(4, 1))
+	require.ErrorIs(err, substrate.ErrInvalidTransaction)
+	require.Contains(err.Error(), "BadProof")
+	require.False(errors.As(err, &de))
+
+	require.NoError(cl.DryRun(user, accept, substrate.WithMortalEra(4)))
+}
@@ -0,0 +1,85 @@
+package substratetest
+
+import (
+	"net"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/require"
+	substrate "github.com/threefoldtech/substrate-client"
+)
+
+func TestMetrics(t *testing.T) {
+	require := require.New(t)
+
+	srv, err := NewServer()
+	require.NoError(err)
+	defer srv.Close()
+
+	reg := prometheus.NewRegistry()
+	metrics, err := substrate.NewMetrics(reg)
+	require.NoError(err)
+
+	mgr := substrate.NewManagerWithOptions([]string{srv.URL()}, substrate.WithMetrics(metrics))
+	defer mgr.Close()
+
+	user, err := substrate.NewIdentityFromEd25519Phrase("//Bob")
+	require.NoError(err)
+
+	// the second client reuses the connection of the first one
+	for i := 0; i < 2; i++ {
+		cl, err := mgr.Substrate()
+		require.NoError(err)
+
+		if i == 0 {
+			require.NoError(cl.AcceptTermsAndConditions(user, "link", "hash"))
+			_, err = cl.CreateTwin(user, net.ParseIP("::1"))
+			require.NoError(err)
+		}
+		cl.Close()
+	}
+
+	families, err := reg.Gather()
+	require.NoError(err)
+
+	gathered := map[string]*dto.MetricFamily{}
+	for _, family := range families {
+		gathered[family.GetName()] = family
+	}
+
+	// value returns the value of the metric with the given labels
+	value := func(name string, labels map[string]string) float64 {
+		family, ok := gathered[name]
+		require.True(ok, name)
+
+	next:
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if v, ok := labels[label.GetName()]; ok && v != label.GetValue() {
+					continue next
+				}
+			}
+
+			switch family.GetType() {
+			case dto.MetricType_COUNTER:
+				return metric.GetCounter().GetValue()
+			case dto.MetricType_GAUGE:
+				return metric.GetGauge().GetValue()
+			case dto.MetricType_HISTOGRAM:
+				return float64(metric.GetHistogram().GetSampleCount())
+			}
+		}
+
+		require.FailNow("metric not found", "%s %v", name, labels)
+		return 0
+	}
+
+	require.NotZero(value("substrate_client_rpc_duration_seconds", map[string]string{"method": "author_submitAndWatchExtrinsic", "status": "ok"}))
+	require.Equal(2.0, value("substrate_client_extrinsic_duration_seconds", map[string]string{"outcome": "in_block"}))
+	// the manager also connects in the background to follow runtime upgrades
+	require.NotZero(value("substrate_client_endpoint_checks_total", map[string]string{"url": srv.URL(), "result": "connected"}))
+	require.Equal(1.0, value("substrate_client_pool_requests_total", map[string]string{"result": "miss"}))
+	require.Equal(1.0, value("substrate_client_pool_requests_total", map[string]string{"result": "hit"}))
+	require.Equal(1.0, value("substrate_client_pool_idle_connections", map[string]string{"url": srv.URL()}))
+}
@@ -0,0 +1,132 @@
+package substratetest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	substrate "github.com/threefoldtech/substrate-client"
+)
+
+// gateTransport holds back submitted extrinsics, the channel of every held
+// extrinsic is sent on held and the extrinsic is sent once it's closed
+type gateTransport struct {
+	substrate.Transport
+	held chan chan struct{}
+}
+
+func (t *gateTransport) Send(msg json.RawMessage) error {
+	if bytes.Contains(msg, []byte(`"author_submitAndWatchExtrinsic"`)) {
+		release := make(chan struct{})
+		t.held <- release
+		<-release
+	}
+
+	return t.Transport.Send(msg)
+}
+
+func TestNonceAfterDispatchError(t *testing.T) {
+	require := require.New(t)
+
+	srv, err := NewServer()
+	require.NoError(err)
+	defer srv.Close()
+
+	held := make(chan chan struct{})
+	dialer := func(ctx context.Context, endpoint string) (substrate.Transport, error) {
+		t, err := substrate.DialWebsocket(ctx, endpoint)
+		if err != nil {
+			return nil, err
+		}
+
+		return &gateTransport{Transport: t, held: held}, nil
+	}
+
+	mgr := substrate.NewManagerWithOptions([]string{srv.URL()}, substrate.WithDialer(dialer))
+	defer mgr.Close()
+
+	user, err := substrate.NewIdentityFromEd25519Phrase("//Bob")
+	require.NoError(err)
+
+	// every client has its own connection, so a held extrinsic
+	// doesn't block the others
+	var clients []*substrate.Substrate
+	for i := 0; i < 3; i++ {
+		cl, err := mgr.Substrate()
+		require.NoError(err)
+		defer cl.Close()
+		clients = append(clients, cl)
+	}
+
+	// the twin can't be created before accepting the terms and conditions,
+	// so the first extrinsic is included with a dispatch error
+	failed := make(chan error, 1)
+	go func() {
+		_, err := clients[0].CreateTwin(user, net.ParseIP("::1"))
+		failed <- err
+	}()
+	releaseFailed := <-held
+
+	// the second extrinsic takes the next nonce, and is held until
+	// the first one failed
+	accepted := make(chan error, 1)
+	go func() {
+		accepted <- clients[1].AcceptTermsAndConditions(user, "link", "hash")
+	}()
+	releaseAccepted := <-held
+
+	close(releaseFailed)
+	require.Error(<-failed)
+
+	// the nonce of the failed extrinsic is consumed, so the nonce held
+	// by the second extrinsic must not be handed out again
+	created := make(chan error, 1)
+	go func() {
+		_, err := clients[2].CreateTwin(user, net.ParseIP("::1"))
+		created <- err
+	}()
+	releaseCreated := <-held
+
+	close(releaseAccepted)
+	require.NoError(<-accepted)
+
+	close(releaseCreated)
+	require.NoError(<-created)
+}
+
+func TestNonceAfterDroppedExtrinsic(t *testing.T) {
+	require := require.New(t)
+
+	srv, err := NewServer()
+	require.NoError(err)
+	defer srv.Close()
+
+	mgr := substrate.NewManager(srv.URL())
+	defer mgr.Close()
+
+	cl, err := mgr.Substrate()
+	require.NoError(err)
+	defer cl.Close()
+
+	user, err := substrate.NewIdentityFromEd25519Phrase("//Bob")
+	require.NoError(err)
+
+	// the extrinsic waits in the pool and nobody watches it anymore
+	srv.Pause()
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	require.Error(cl.WithContext(ctx).AcceptTermsAndConditions(user, "link", "hash"))
+
+	// the pool drops it, so its nonce is never consumed
+	srv.DropPool()
+	require.NoError(srv.Resume())
+
+	// the next extrinsic must not wait for the dropped nonce
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	require.NoError(cl.WithContext(ctx).AcceptTermsAndConditions(user, "link", "hash"))
+}
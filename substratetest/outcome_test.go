@@ -0,0 +1,79 @@
+package substratetest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"sync/atomic"
+	"testing"
+
+	"github.com/centrifuge/go-substrate-rpc-client/v4/types"
+	"github.com/stretchr/testify/require"
+	substrate "github.com/threefoldtech/substrate-client"
+)
+
+// eventsKey is the storage key of System.Events
+const eventsKey = "0x26aa394eea5630e07c48ae0c9558cef780d41e5e16056765bc8461851072c9d7"
+
+// eventsTransport fails the reads of the block events while fail is set
+type eventsTransport struct {
+	substrate.Transport
+	fail *int32
+}
+
+func (t *eventsTransport) Send(msg json.RawMessage) error {
+	if atomic.LoadInt32(t.fail) == 1 && bytes.Contains(msg, []byte(eventsKey)) {
+		msg = bytes.Replace(msg, []byte(`"state_getStorage"`), []byte(`"state_unavailable"`), 1)
+	}
+
+	return t.Transport.Send(msg)
+}
+
+func TestUnknownOutcome(t *testing.T) {
+	require := require.New(t)
+
+	srv, err := NewServer()
+	require.NoError(err)
+	defer srv.Close()
+
+	var fail int32
+	dialer := func(ctx context.Context, endpoint string) (substrate.Transport, error) {
+		t, err := substrate.DialWebsocket(ctx, endpoint)
+		if err != nil {
+			return nil, err
+		}
+
+		return &eventsTransport{Transport: t, fail: &fail}, nil
+	}
+
+	mgr := substrate.NewManagerWithOptions([]string{srv.URL()}, substrate.WithDialer(dialer))
+	defer mgr.Close()
+
+	cl, err := mgr.Substrate()
+	require.NoError(err)
+	defer cl.Close()
+
+	user, err := substrate.NewIdentityFromEd25519Phrase("//Bob")
+	require.NoError(err)
+
+	conn, meta, err := cl.GetClient()
+	require.NoError(err)
+
+	call, err := types.NewCall(meta, "TfgridModule.user_accept_tc", "link", "hash")
+	require.NoError(err)
+
+	// the extrinsic is included, but its events can't be read
+	atomic.StoreInt32(&fail, 1)
+	receipt, err := cl.Submit(conn, meta, user, call)
+	atomic.StoreInt32(&fail, 0)
+
+	require.ErrorIs(err, substrate.ErrUnknownOutcome)
+	require.NotEqual(types.Hash{}, receipt.BlockHash)
+	require.Equal(srv.Height(), receipt.BlockNumber)
+	require.False(receipt.Success)
+
+	// the terms and conditions were accepted
+	_, err = cl.CreateTwin(user, net.ParseIP("::1"))
+	require.NoError(err)
+}
@@ -0,0 +1,183 @@
+package substratetest
+
+import (
+	"math/big"
+
+	"github.com/centrifuge/go-substrate-rpc-client/v4/types"
+	substrate "github.com/threefoldtech/substrate-client"
+)
+
+const (
+	system    = "System"
+	timestamp = "Timestamp"
+	aura      = "Aura"
+	sudo      = "Sudo"
+	utility   = "Utility"
+)
+
+func systemPallet() pallet {
+	return pallet{
+		name:  system,
+		index: 0,
+		storage: []entry{
+			plain("Number", types.U32(0)),
+			// events are encoded by the chain when a block is sealed
+			plain("Events", []byte{}),
+			mapped("Account", types.AccountInfo{}, types.AccountID{}),
+		},
+	}
+}
+
+func timestampPallet() pallet {
+	return pallet{
+		name:  timestamp,
+		index: 1,
+		storage: []entry{
+			plain("Now", types.U64(0)),
+		},
+		calls: []call{
+			{name: "set", fn: setTimestamp},
+		},
+	}
+}
+
+func auraPallet() pallet {
+	return pallet{
+		name:  aura,
+		index: 3,
+		storage: []entry{
+			plain("Authorities", []substrate.AccountID{}),
+		},
+	}
+}
+
+func sudoPallet() pallet {
+	return pallet{
+		name:  sudo,
+		index: 8,
+		storage: []entry{
+			plain("Key", types.AccountID{}),
+		},
+		calls: []call{
+			{name: "sudo", fn: sudoCall},
+		},
+		errors: []string{"RequireSudo"},
+	}
+}
+
+func utilityPallet() pallet {
+	return pallet{
+		name:  utility,
+		index: 5,
+		calls: []call{
+			{name: "batch", fn: batchCall},
+			{name: "batch_all", fn: batchAllCall},
+		},
+	}
+}
+
+type setTimestampArgs struct {
+	Now types.UCompact
+}
+
+// setTimestamp is the inherent that sets the time of the block
+func setTimestamp(d *dispatch, args setTimestampArgs) error {
+	if d.origin != nil || d.root {
+		return errBadOrigin
+	}
+
+	now := big.Int(args.Now)
+	d.put(types.U64(now.Uint64()), timestamp, "Now")
+	return nil
+}
+
+type sudoArgs struct {
+	Call types.Call
+}
+
+// sudoCall dispatches the call with root origin, it only fails if the
+// signer is not the sudo key, the result of the call is in the Sudid event
+func sudoCall(d *dispatch, args sudoArgs) error {
+	signer, err := d.signer()
+	if err != nil {
+		return err
+	}
+
+	var key types.AccountID
+	if !d.get(&key, sudo, "Key") || key != signer {
+		return d.fail(sudo, "RequireSudo")
+	}
+
+	root := d.nested()
+	root.origin = nil
+	root.root = true
+
+	var result dispatchResult
+	if err := d.rt.dispatch(root, args.Call); err != nil {
+		derr := d.rt.dispatchError(err)
+		result.err = &derr
+	} else {
+		root.commit(d)
+	}
+
+	d.emit(sudo, "Sudid", result)
+	return nil
+}
+
+type batchArgs struct {
+	Calls batchCalls
+}
+
+// batchCall dispatches the calls until one of them fails, the batch
+// succeeds and the failure is reported in the BatchInterrupted event
+func batchCall(d *dispatch, args batchArgs) error {
+	calls, err := d.rt.split(args.Calls)
+	if err != nil {
+		return err
+	}
+
+	for i, call := range calls {
+		if err := d.rt.dispatch(d, call); err != nil {
+			d.emit(utility, "BatchInterrupted", types.U32(i), d.rt.dispatchError(err))
+			return nil
+		}
+
+		d.emit(utility, "ItemCompleted")
+	}
+
+	d.emit(utility, "BatchCompleted")
+	return nil
+}
+
+// batchAllCall dispatches all the calls, the batch fails if any of them fails
+func batchAllCall(d *dispatch, args batchArgs) error {
+	calls, err := d.rt.split(args.Calls)
+	if err != nil {
+		return err
+	}
+
+	for _, call := range calls {
+		if err := d.rt.dispatch(d, call); err != nil {
+			return err
+		}
+
+		d.emit(utility, "ItemCompleted")
+	}
+
+	d.emit(utility, "BatchCompleted")
+	return nil
+}
+
+// account returns the info of the account, missing accounts have no balance
+func (d *dispatch) account(id types.AccountID) types.AccountInfo {
+	var info types.AccountInfo
+	if !d.get(&info, system, "Account", id) {
+		zero := types.NewU128(big.Int{})
+		info.Data.Free = zero
+		info.Data.Reserved = zero
+		info.Data.MiscFrozen = zero
+		info.Data.FreeFrozen = zero
+	}
+
+	return info
+}
@@ -0,0 +1,107 @@
+package substratetest
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	substrate "github.com/threefoldtech/substrate-client"
+)
+
+func TestProcessor(t *testing.T) {
+	require := require.New(t)
+
+	srv, err := NewServer(WithBlockTime(time.Hour))
+	require.NoError(err)
+	defer srv.Close()
+
+	// history to catch up with
+	for i := 0; i < 25; i++ {
+		require.NoError(srv.NewBlock())
+	}
+	head := srv.Height()
+
+	mgr := substrate.NewManager(srv.URL())
+	defer mgr.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	// new blocks are only produced once the history is processed, so
+	// the blocks after head are processed while following the chain
+	var produce sync.Once
+	startProducing := func() {
+		go func() {
+			time.Sleep(100 * time.Millisecond)
+			for ctx.Err() == nil {
+				if err := srv.NewBlock(); err != nil {
+					t.Error(err)
+					return
+				}
+				time.Sleep(20 * time.Millisecond)
+			}
+		}()
+	}
+
+	store := substrate.NewFileCheckpointStore(filepath.Join(t.TempDir(), "checkpoint"))
+	failAt := head + 10
+	errFailed := fmt.Errorf("handler failed")
+
+	var processed []uint32
+	handler := func(events substrate.BlockEvents) error {
+		if events.Number == head {
+			produce.Do(startProducing)
+		}
+
+		if events.Number == failAt {
+			return errFailed
+		}
+
+		processed = append(processed, events.Number)
+		return nil
+	}
+
+	processor := substrate.NewProcessor(mgr, store, handler, substrate.WithChunkSize(10))
+	require.ErrorIs(processor.Run(ctx), errFailed)
+
+	// every block up to the failed one is processed once and in order
+	require.Len(processed, int(failAt-1))
+	for i, number := range processed {
+		require.EqualValues(i+1, number)
+	}
+
+	// the checkpoint doesn't advance past the failed block
+	checkpoint, ok, err := store.Get()
+	require.NoError(err)
+	require.True(ok)
+	require.Equal(failAt-1, checkpoint)
+
+	// after a restart processing continues with the failed block
+	runCtx, stop := context.WithCancel(ctx)
+	defer stop()
+
+	processed = nil
+	handler = func(events substrate.BlockEvents) error {
+		processed = append(processed, events.Number)
+		if len(processed) == 10 {
+			stop()
+		}
+		return nil
+	}
+
+	processor = substrate.NewProcessor(mgr, store, handler)
+	require.ErrorIs(processor.Run(runCtx), context.Canceled)
+
+	require.GreaterOrEqual(len(processed), 10)
+	for i, number := range processed {
+		require.Equal(failAt+uint32(i), number)
+	}
+
+	checkpoint, _, err = store.Get()
+	require.NoError(err)
+	require.Equal(processed[len(processed)-1], checkpoint)
+}
@@ -0,0 +1,56 @@
+package substratetest
+
+import (
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	substrate "github.com/threefoldtech/substrate-client"
+)
+
+func TestRecordReplay(t *testing.T) {
+	require := require.New(t)
+
+	session := func(mgr substrate.Manager) *substrate.Twin {
+		defer mgr.Close()
+
+		cl, err := mgr.Substrate()
+		require.NoError(err)
+		defer cl.Close()
+
+		// sr25519 signatures are randomized so the extrinsics would
+		// not be found in the recorded blocks
+		identity, err := substrate.NewIdentityFromEd25519Phrase("//Bob")
+		require.NoError(err)
+
+		require.NoError(cl.AcceptTermsAndConditions(identity, "link", "hash"))
+		id, err := cl.CreateTwin(identity, net.ParseIP("::1"))
+		require.NoError(err)
+
+		twin, err := cl.GetTwin(id)
+		require.NoError(err)
+		return twin
+	}
+
+	srv, err := NewServer()
+	require.NoError(err)
+
+	rec := substrate.NewRecorder(substrate.DialWebsocket)
+	recorded := session(substrate.NewManagerWithOptions([]string{srv.URL()}, substrate.WithDialer(rec.Dial)))
+	require.NoError(srv.Close())
+
+	path := filepath.Join(t.TempDir(), "session.json")
+	require.NoError(rec.Save(path))
+
+	rep, err := substrate.LoadReplayer(path)
+	require.NoError(err)
+
+	// the server is gone, everything is answered from the recording
+	replayed := session(substrate.NewManagerWithOptions(
+		[]string{"ws://replay"},
+		substrate.WithDialer(rep.Dial),
+		substrate.WithAcceptableDelay(0),
+	))
+	require.Equal(recorded, replayed)
+}
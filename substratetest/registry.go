@@ -0,0 +1,252 @@
+package substratetest
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/centrifuge/go-substrate-rpc-client/v4/scale"
+	"github.com/centrifuge/go-substrate-rpc-client/v4/types"
+	substrate "github.com/threefoldtech/substrate-client"
+)
+
+var (
+	encodeable = reflect.TypeOf((*scale.Encodeable)(nil)).Elem()
+	decodeable = reflect.TypeOf((*scale.Decodeable)(nil)).Elem()
+)
+
+// variant is a variant of an enum with custom scale encoding,
+// fields are the types of the (unnamed) variant fields
+type variant struct {
+	name   string
+	fields []reflect.Type
+}
+
+// registry builds the portable types of the metadata out of go types. Plain
+// go types are described the way the scale codec encodes them, types with
+// a custom encoding must be described in custom.
+type registry struct {
+	types []types.PortableTypeV14
+	ids   map[reflect.Type]types.Si1LookupTypeID
+}
+
+func newRegistry() *registry {
+	return &registry{ids: make(map[reflect.Type]types.Si1LookupTypeID)}
+}
+
+func typeOf(v interface{}) reflect.Type {
+	return reflect.TypeOf(v)
+}
+
+// enum describes an enum with no data in any variant
+func enum(names ...string) []variant {
+	variants := make([]variant, 0, len(names))
+	for _, name := range names {
+		variants = append(variants, variant{name: name})
+	}
+
+	return variants
+}
+
+// option describes an option of the given type
+func option(t reflect.Type) []variant {
+	return []variant{{name: "None"}, {name: "Some", fields: []reflect.Type{t}}}
+}
+
+// custom are the types that implement their own scale encoding
+var custom = map[reflect.Type][]variant{
+	typeOf(substrate.Role{}):                     enum("Node", "Gateway"),
+	typeOf(substrate.NodeCertification{}):        enum("Diy", "Certified"),
+	typeOf(substrate.FarmCertification{}):        enum("NotCertified", "Gold"),
+	typeOf(substrate.DeletedState{}):             enum("CanceledByUser", "OutOfFunds", "CanceledByCollective"),
+	typeOf(substrate.DiscountLevel{}):            enum("None", "Default", "Bronze", "Silver", "Gold"),
+	typeOf(substrate.ServiceContractState{}):     enum("Created", "AgreementReady", "ApprovedByBoth"),
+	typeOf(substrate.Cause{}):                    enum("CanceledByUser", "OutOfFunds"),
+	typeOf(substrate.Power{}):                    enum("Up", "Down"),
+	typeOf(substrate.OptionPublicConfig{}):       option(typeOf(substrate.PublicConfig{})),
+	typeOf(substrate.OptionFarmingPolicyLimit{}): option(typeOf(substrate.FarmingPolicyLimit{})),
+	typeOf(types.OptionU32{}):                    option(typeOf(types.U32(0))),
+	typeOf(types.OptionU64{}):                    option(typeOf(types.U64(0))),
+	typeOf(types.DispatchClass{}):                enum("Normal", "Operational", "Mandatory"),
+	typeOf(types.Pays{}):                         enum("Yes", "No"),
+	typeOf(substrate.PowerState{}): {
+		{name: "Up"},
+		{name: "Down", fields: []reflect.Type{typeOf(types.U32(0))}},
+	},
+	typeOf(substrate.ContractState{}): {
+		{name: "Created"},
+		{name: "Deleted", fields: []reflect.Type{typeOf(substrate.DeletedState{})}},
+		{name: "GracePeriod", fields: []reflect.Type{typeOf(types.U64(0))}},
+	},
+	typeOf(substrate.ContractType{}): {
+		{name: "NodeContract", fields: []reflect.Type{typeOf(substrate.NodeContract{})}},
+		{name: "NameContract", fields: []reflect.Type{typeOf(substrate.NameContract{})}},
+		{name: "RentContract", fields: []reflect.Type{typeOf(substrate.RentContract{})}},
+	},
+	// events use dispatchError to encode errors like substrate does
+	typeOf(types.DispatchError{}): {
+		{name: "Other"},
+		{name: "CannotLookup"},
+		{name: "BadOrigin"},
+		{name: "Module", fields: []reflect.Type{typeOf(moduleError{})}},
+	},
+	typeOf(types.DispatchResult{}): {
+		{name: "Ok"},
+		{name: "Err", fields: []reflect.Type{typeOf(types.DispatchError{})}},
+	},
+}
+
+// moduleError is the module variant of a dispatch error
+type moduleError struct {
+	Index types.U8
+	Error types.U8
+}
+
+var (
+	// accountTypes are encoded as AccountId32 so they are
+	// shown as addresses in decoded calls
+	accountTypes = map[reflect.Type]bool{
+		typeOf(types.AccountID{}):     true,
+		typeOf(substrate.AccountID{}): true,
+	}
+
+	primitives = map[reflect.Kind]types.Si0TypeDefPrimitive{
+		reflect.Bool:   types.IsBool,
+		reflect.String: types.IsStr,
+		reflect.Uint8:  types.IsU8,
+		reflect.Uint16: types.IsU16,
+		reflect.Uint32: types.IsU32,
+		reflect.Uint64: types.IsU64,
+		reflect.Int8:   types.IsI8,
+		reflect.Int16:  types.IsI16,
+		reflect.Int32:  types.IsI32,
+		reflect.Int64:  types.IsI64,
+	}
+)
+
+// register returns the id of the type, adding it to the registry if needed
+func (r *registry) register(t reflect.Type) types.Si1LookupTypeID {
+	if id, ok := r.ids[t]; ok {
+		return id
+	}
+
+	// the id is reserved first so recursive types work
+	id := types.NewSi1LookupTypeIDFromUInt(uint64(len(r.types)))
+	index := len(r.types)
+	r.ids[t] = id
+	r.types = append(r.types, types.PortableTypeV14{ID: id})
+
+	typ := types.Si1Type{Def: r.def(t)}
+	if accountTypes[t] {
+		typ.Path = types.Si1Path{"sp_core", "crypto", "AccountId32"}
+	} else if t.Name() != "" && t.PkgPath() != "" {
+		pkg := t.PkgPath()
+		typ.Path = types.Si1Path{types.Text(pkg[strings.LastIndex(pkg, "/")+1:]), types.Text(t.Name())}
+	}
+
+	r.types[index].Type = typ
+	return id
+}
+
+// add adds an anonymous type with the given definition
+func (r *registry) add(def types.Si1TypeDef) types.Si1LookupTypeID {
+	id := types.NewSi1LookupTypeIDFromUInt(uint64(len(r.types)))
+	r.types = append(r.types, types.PortableTypeV14{ID: id, Type: types.Si1Type{Def: def}})
+	return id
+}
+
+func (r *registry) def(t reflect.Type) types.Si1TypeDef {
+	if variants, ok := custom[t]; ok {
+		return r.variants(variants)
+	}
+
+	switch t {
+	case typeOf(types.U128{}):
+		return primitive(types.IsU128)
+	case typeOf(types.UCompact{}):
+		return types.Si1TypeDef{IsCompact: true, Compact: types.Si1TypeDefCompact{Type: r.register(typeOf(types.U128{}))}}
+	}
+
+	if accountTypes[t] {
+		return composite(types.Si1Field{Type: r.register(reflect.ArrayOf(32, typeOf(byte(0))))})
+	}
+
+	if t.Implements(encodeable) || reflect.PtrTo(t).Implements(decodeable) {
+		panic(fmt.Sprintf("type %s has a custom encoding but no description", t))
+	}
+
+	if primitive, ok := primitives[t.Kind()]; ok {
+		return types.Si1TypeDef{IsPrimitive: true, Primitive: types.Si1TypeDefPrimitive{Si0TypeDefPrimitive: primitive}}
+	}
+
+	switch t.Kind() {
+	case reflect.Slice:
+		return types.Si1TypeDef{IsSequence: true, Sequence: types.Si1TypeDefSequence{Type: r.register(t.Elem())}}
+	case reflect.Array:
+		return types.Si1TypeDef{IsArray: true, Array: types.Si1TypeDefArray{Len: types.U32(t.Len()), Type: r.register(t.Elem())}}
+	case reflect.Struct:
+		return composite(r.fields(t)...)
+	}
+
+	panic(fmt.Sprintf("type %s is not supported", t))
+}
+
+// fields returns the named fields of a struct as encoded by the scale codec
+func (r *registry) fields(t reflect.Type) []types.Si1Field {
+	var fields []types.Si1Field
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" || field.Tag.Get("scale") == "-" {
+			continue
+		}
+
+		fields = append(fields, types.Si1Field{
+			HasName:     true,
+			Name:        types.Text(snakeCase(field.Name)),
+			Type:        r.register(field.Type),
+			HasTypeName: field.Type.Name() != "",
+			TypeName:    types.Text(field.Type.Name()),
+		})
+	}
+
+	return fields
+}
+
+// variants builds an enum type
+func (r *registry) variants(variants []variant) types.Si1TypeDef {
+	def := types.Si1TypeDef{IsVariant: true}
+	for i, v := range variants {
+		variant := types.Si1Variant{Name: types.Text(v.name), Index: types.U8(i)}
+		for _, field := range v.fields {
+			variant.Fields = append(variant.Fields, types.Si1Field{Type: r.register(field)})
+		}
+
+		def.Variant.Variants = append(def.Variant.Variants, variant)
+	}
+
+	return def
+}
+
+func primitive(p types.Si0TypeDefPrimitive) types.Si1TypeDef {
+	return types.Si1TypeDef{IsPrimitive: true, Primitive: types.Si1TypeDefPrimitive{Si0TypeDefPrimitive: p}}
+}
+
+func composite(fields ...types.Si1Field) types.Si1TypeDef {
+	return types.Si1TypeDef{IsComposite: true, Composite: types.Si1TypeDefComposite{Fields: fields}}
+}
+
+// snakeCase converts a go field name to the rust naming, for example
+// FarmID becomes farm_id
+func snakeCase(name string) string {
+	var b strings.Builder
+	runes := []rune(name)
+	for i, c := range runes {
+		if unicode.IsUpper(c) && i > 0 && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1])) {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToLower(c))
+	}
+
+	return b.String()
+}
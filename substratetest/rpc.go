@@ -0,0 +1,334 @@
+package substratetest
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/centrifuge/go-substrate-rpc-client/v4/types"
+	"github.com/pkg/errors"
+	substrate "github.com/threefoldtech/substrate-client"
+	"golang.org/x/crypto/blake2b"
+)
+
+// method is a JSON-RPC method served by the chain
+type method func(c *Chain, params []json.RawMessage) (interface{}, error)
+
+// subscribe starts a subscription, notifications are sent with sub.notify
+type subscribe struct {
+	notification string
+	fn           func(c *Chain, sub *subscription, params []json.RawMessage) error
+}
+
+var (
+	methods = map[string]method{
+		"chain_getBlockHash":      getBlockHash,
+		"chain_getBlock":          getBlock,
+		"chain_getHeader":         getHeader,
+		"chain_getFinalizedHead":  getFinalizedHead,
+		"state_getMetadata":       getMetadata,
+		"state_getRuntimeVersion": getRuntimeVersion,
+		"state_getStorage":        getStorage,
+		"state_queryStorage":      queryStorage,
+		"system_accountNextIndex": accountNextIndex,
+		"system_dryRun":           dryRun,
+		"payment_queryInfo":       queryInfo,
+		"author_submitExtrinsic":  submitExtrinsic,
+	}
+
+	subscriptions = map[string]subscribe{
+		"chain_subscribeNewHead":         {notification: "chain_newHead", fn: subscribeHeads},
+		"chain_subscribeNewHeads":        {notification: "chain_newHead", fn: subscribeHeads},
+		"chain_subscribeFinalizedHeads":  {notification: "chain_finalizedHead", fn: subscribeHeads},
+		"state_subscribeRuntimeVersion":  {notification: "state_runtimeVersion", fn: subscribeRuntimeVersion},
+		"author_submitAndWatchExtrinsic": {notification: "author_extrinsicUpdate", fn: submitAndWatchExtrinsic},
+	}
+)
+
+// at returns the block with the hash at index i of the params, or the head
+func (c *Chain) at(params []json.RawMessage, i int) (*block, error) {
+	hash, err := hashParam(params, i)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.block(hash)
+}
+
+func getBlockHash(c *Chain, params []json.RawMessage) (interface{}, error) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	number := uint64(c.head().header.Number)
+	if err := param(params, 0, &number); err != nil {
+		return nil, err
+	}
+
+	if number >= uint64(len(c.blocks)) {
+		return nil, nil
+	}
+
+	return c.blocks[number].hash, nil
+}
+
+func getBlock(c *Chain, params []json.RawMessage) (interface{}, error) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	b, err := c.at(params, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return types.SignedBlock{
+		Block: types.Block{Header: b.header, Extrinsics: b.extrinsics},
+	}, nil
+}
+
+func getHeader(c *Chain, params []json.RawMessage) (interface{}, error) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	b, err := c.at(params, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return b.header, nil
+}
+
+// getFinalizedHead returns the head, all blocks are final
+func getFinalizedHead(c *Chain, params []json.RawMessage) (interface{}, error) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	return c.head().hash, nil
+}
+
+func getMetadata(c *Chain, params []json.RawMessage) (interface{}, error) {
+	return types.EncodeToHexString(c.rt.meta)
+}
+
+func runtimeVersion() types.RuntimeVersion {
+	return types.RuntimeVersion{
+		APIs:               []types.RuntimeVersionAPI{},
+		AuthoringVersion:   1,
+		ImplName:           specName,
+		ImplVersion:        1,
+		SpecName:           specName,
+		SpecVersion:        specVersion,
+		TransactionVersion: transactionVersion,
+	}
+}
+
+func getRuntimeVersion(c *Chain, params []json.RawMessage) (interface{}, error) {
+	return runtimeVersion(), nil
+}
+
+func getStorage(c *Chain, params []json.RawMessage) (interface{}, error) {
+	var key string
+	if err := param(params, 0, &key); err != nil {
+		return nil, err
+	}
+
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	b, err := c.at(params, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := types.HexDecodeString(key)
+	if err != nil {
+		return nil, invalidParams(err)
+	}
+
+	value, ok := b.storage[string(raw)]
+	if !ok {
+		return nil, nil
+	}
+
+	return types.HexEncodeToString(value), nil
+}
+
+// queryStorage returns the changes of the keys in the blocks from the start
+// block up to the given block, the first change set has all the keys
+func queryStorage(c *Chain, params []json.RawMessage) (interface{}, error) {
+	var keys []string
+	if err := param(params, 0, &keys); err != nil {
+		return nil, err
+	}
+
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	from, err := c.at(params, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	to, err := c.at(params, 2)
+	if err != nil {
+		return nil, err
+	}
+
+	raw := make([]string, 0, len(keys))
+	for _, key := range keys {
+		data, err := types.HexDecodeString(key)
+		if err != nil {
+			return nil, invalidParams(err)
+		}
+		raw = append(raw, string(data))
+	}
+
+	sets := []interface{}{}
+	for n := from.header.Number; n <= to.header.Number; n++ {
+		b := c.blocks[n]
+
+		var changes [][]interface{}
+		for i, key := range raw {
+			value, ok := b.storage[key]
+			if n != from.header.Number {
+				old, had := c.blocks[n-1].storage[key]
+				if ok == had && string(old) == string(value) {
+					continue
+				}
+			}
+
+			change := []interface{}{keys[i], nil}
+			if ok {
+				change[1] = types.HexEncodeToString(value)
+			}
+			changes = append(changes, change)
+		}
+
+		if len(changes) > 0 {
+			sets = append(sets, map[string]interface{}{"block": b.hash, "changes": changes})
+		}
+	}
+
+	return sets, nil
+}
+
+func accountNextIndex(c *Chain, params []json.RawMessage) (interface{}, error) {
+	var address string
+	if err := param(params, 0, &address); err != nil {
+		return nil, err
+	}
+
+	account, err := substrate.FromAddress(address)
+	if err != nil {
+		return nil, invalidParams(errors.Wrap(err, "invalid address"))
+	}
+
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	return c.poolNonce(types.AccountID(account)), nil
+}
+
+func extrinsicParam(params []json.RawMessage) (types.Extrinsic, error) {
+	var ext types.Extrinsic
+	var hex string
+	if err := param(params, 0, &hex); err != nil {
+		return ext, err
+	}
+
+	if err := types.DecodeFromHexString(hex, &ext); err != nil {
+		return ext, invalidParams(errors.Wrap(err, "invalid extrinsic"))
+	}
+
+	return ext, nil
+}
+
+func dryRun(c *Chain, params []json.RawMessage) (interface{}, error) {
+	ext, err := extrinsicParam(params)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := c.dryRun(ext)
+	if err != nil {
+		return nil, err
+	}
+
+	return types.HexEncodeToString(result), nil
+}
+
+// byteFee is the fee per byte of extrinsic estimated by queryInfo,
+// blocks don't charge it
+const byteFee = 1000
+
+// queryInfo estimates the fee from the length of the extrinsic, like
+// substrate the extrinsic is not validated
+func queryInfo(c *Chain, params []json.RawMessage) (interface{}, error) {
+	ext, err := extrinsicParam(params)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := types.EncodeToBytes(ext)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"weight":     0,
+		"class":      "normal",
+		"partialFee": strconv.Itoa(byteFee * len(data)),
+	}, nil
+}
+
+// poolError converts the errors of the transaction pool to
+// the RPC errors substrate returns
+func poolError(err error) error {
+	var invalid invalidTransaction
+	if errors.As(err, &invalid) {
+		return &rpcError{Code: errInvalidTransaction, Message: "Invalid Transaction", Data: invalid.Error()}
+	}
+
+	return err
+}
+
+func submitExtrinsic(c *Chain, params []json.RawMessage) (interface{}, error) {
+	ext, err := extrinsicParam(params)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.submit(ext, func(types.ExtrinsicStatus) {}); err != nil {
+		return nil, poolError(err)
+	}
+
+	data, err := types.EncodeToBytes(ext)
+	if err != nil {
+		return nil, err
+	}
+
+	return types.Hash(blake2b.Sum256(data)), nil
+}
+
+func submitAndWatchExtrinsic(c *Chain, sub *subscription, params []json.RawMessage) error {
+	ext, err := extrinsicParam(params)
+	if err != nil {
+		return err
+	}
+
+	return poolError(c.submit(ext, func(status types.ExtrinsicStatus) {
+		sub.notify(status)
+	}))
+}
+
+func subscribeHeads(c *Chain, sub *subscription, params []json.RawMessage) error {
+	sub.onCancel(c.subscribe(func(header types.Header) {
+		sub.notify(header)
+	}))
+
+	return nil
+}
+
+// subscribeRuntimeVersion sends the runtime version once, it never changes
+func subscribeRuntimeVersion(c *Chain, sub *subscription, params []json.RawMessage) error {
+	sub.notify(runtimeVersion())
+	return nil
+}
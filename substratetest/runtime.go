@@ -0,0 +1,323 @@
+package substratetest
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/centrifuge/go-substrate-rpc-client/v4/types"
+	"github.com/pkg/errors"
+	substrate "github.com/threefoldtech/substrate-client"
+)
+
+const (
+	specName    = "substrate-threefold"
+	specVersion = 1
+	// transactionVersion is the version of the extrinsics format
+	transactionVersion = 1
+)
+
+// call is a dispatchable of a pallet. fn must be a function of the form
+// func(*dispatch, args T) error where T is a struct of the call arguments
+// in the order they are encoded.
+type call struct {
+	name string
+	fn   interface{}
+}
+
+// entry is a storage item of a pallet, keys are empty for plain values
+type entry struct {
+	name  string
+	value reflect.Type
+	keys  []reflect.Type
+}
+
+func plain(name string, value interface{}) entry {
+	return entry{name: name, value: typeOf(value)}
+}
+
+// mapped is a storage map, all keys are hashed with Blake2_128Concat
+func mapped(name string, value interface{}, keys ...interface{}) entry {
+	e := entry{name: name, value: typeOf(value)}
+	for _, key := range keys {
+		e.keys = append(e.keys, typeOf(key))
+	}
+
+	return e
+}
+
+type pallet struct {
+	name    string
+	index   uint8
+	storage []entry
+	calls   []call
+	errors  []string
+}
+
+// runtime is the set of pallets served by the mock chain
+type runtime struct {
+	pallets []pallet
+	meta    *types.Metadata
+	// events maps pallet and event names to the event index
+	events map[string]types.EventID
+	// handlers maps call indexes to the call implementation
+	handlers map[types.CallIndex]handler
+}
+
+// handler is a call ready to be dispatched
+type handler struct {
+	pallet string
+	name   string
+	args   reflect.Type
+	fn     reflect.Value
+}
+
+func newRuntime(pallets ...pallet) (*runtime, error) {
+	rt := &runtime{
+		pallets:  pallets,
+		events:   make(map[string]types.EventID),
+		handlers: make(map[types.CallIndex]handler),
+	}
+
+	meta, err := rt.metadata()
+	if err != nil {
+		return nil, err
+	}
+
+	// the metadata is encoded and decoded again, so the lookup
+	// tables are built exactly like the client does
+	data, err := types.EncodeToBytes(meta)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode metadata")
+	}
+
+	rt.meta = &types.Metadata{}
+	if err := types.DecodeFromBytes(data, rt.meta); err != nil {
+		return nil, errors.Wrap(err, "failed to decode metadata")
+	}
+
+	return rt, nil
+}
+
+// metadata builds the V14 metadata of the runtime
+func (rt *runtime) metadata() (types.Metadata, error) {
+	r := newRegistry()
+
+	// the runtime call enum is referenced by calls that take
+	// other calls (like sudo and batch) so its id is reserved first
+	outer := r.add(types.Si1TypeDef{})
+	r.ids[typeOf(types.Call{})] = outer
+	r.ids[typeOf(batchCalls{})] = r.add(types.Si1TypeDef{IsSequence: true, Sequence: types.Si1TypeDefSequence{Type: outer}})
+
+	events := eventTypes()
+
+	var calls types.Si1TypeDef
+	calls.IsVariant = true
+
+	v14 := types.MetadataV14{}
+	for _, pallet := range rt.pallets {
+		meta := types.PalletMetadataV14{
+			Name:  types.Text(pallet.name),
+			Index: types.U8(pallet.index),
+		}
+
+		if len(pallet.storage) > 0 {
+			meta.HasStorage = true
+			meta.Storage.Prefix = types.Text(pallet.name)
+			for _, e := range pallet.storage {
+				meta.Storage.Items = append(meta.Storage.Items, r.entry(e))
+			}
+		}
+
+		if len(pallet.calls) > 0 {
+			def, err := rt.calls(r, pallet)
+			if err != nil {
+				return types.Metadata{}, err
+			}
+
+			meta.HasCalls = true
+			meta.Calls.Type = r.add(def)
+			calls.Variant.Variants = append(calls.Variant.Variants, types.Si1Variant{
+				Name:   types.Text(pallet.name),
+				Index:  types.U8(pallet.index),
+				Fields: []types.Si1Field{{Type: meta.Calls.Type}},
+			})
+		}
+
+		if variants := events[pallet.name]; len(variants) > 0 {
+			def := types.Si1TypeDef{IsVariant: true}
+			for i, event := range variants {
+				name := strings.TrimPrefix(event.Name, pallet.name+"_")
+				rt.events[fmt.Sprintf("%s.%s", pallet.name, name)] = types.EventID{pallet.index, uint8(i)}
+
+				variant := types.Si1Variant{Name: types.Text(name), Index: types.U8(i)}
+				for _, field := range eventFields(event.Type.Elem()) {
+					variant.Fields = append(variant.Fields, types.Si1Field{
+						HasName: true,
+						Name:    types.Text(snakeCase(field.Name)),
+						Type:    r.register(field.Type),
+					})
+				}
+				def.Variant.Variants = append(def.Variant.Variants, variant)
+			}
+
+			meta.HasEvents = true
+			meta.Events.Type = r.add(def)
+		}
+
+		if len(pallet.errors) > 0 {
+			meta.HasErrors = true
+			meta.Errors.Type = r.add(r.variants(enum(pallet.errors...)))
+		}
+
+		v14.Pallets = append(v14.Pallets, meta)
+	}
+
+	r.types[outer.Int64()].Type.Def = calls
+
+	v14.Extrinsic = types.ExtrinsicV14{
+		Type:    r.register(typeOf([]byte{})),
+		Version: 4,
+	}
+	v14.Type = r.register(typeOf(struct{}{}))
+	v14.Lookup.Types = r.types
+
+	return types.Metadata{
+		MagicNumber:   types.MagicNumber,
+		Version:       14,
+		AsMetadataV14: v14,
+	}, nil
+}
+
+// entry builds the metadata of a storage item
+func (r *registry) entry(e entry) types.StorageEntryMetadataV14 {
+	item := types.StorageEntryMetadataV14{
+		Name:     types.Text(e.name),
+		Modifier: types.StorageFunctionModifierV0{IsOptional: true},
+	}
+
+	value := r.register(e.value)
+	if len(e.keys) == 0 {
+		item.Type = types.StorageEntryTypeV14{IsPlainType: true, AsPlainType: value}
+		return item
+	}
+
+	key := r.register(e.keys[0])
+	hashers := []types.StorageHasherV10{{IsBlake2_128Concat: true}}
+	if len(e.keys) > 1 {
+		var tuple types.Si1TypeDefTuple
+		for _, k := range e.keys {
+			tuple = append(tuple, r.register(k))
+		}
+		key = r.add(types.Si1TypeDef{IsTuple: true, Tuple: tuple})
+
+		for range e.keys[1:] {
+			hashers = append(hashers, types.StorageHasherV10{IsBlake2_128Concat: true})
+		}
+	}
+
+	item.Type = types.StorageEntryTypeV14{
+		IsMap: true,
+		AsMap: types.MapTypeV14{Hashers: hashers, Key: key, Value: value},
+	}
+
+	return item
+}
+
+// calls builds the call enum of the pallet and registers the call handlers
+func (rt *runtime) calls(r *registry, pallet pallet) (types.Si1TypeDef, error) {
+	def := types.Si1TypeDef{IsVariant: true}
+	for i, c := range pallet.calls {
+		fn := reflect.ValueOf(c.fn)
+		typ := fn.Type()
+		if typ.Kind() != reflect.Func || typ.NumIn() != 2 || typ.In(0) != typeOf(&dispatch{}) ||
+			typ.In(1).Kind() != reflect.Struct || typ.NumOut() != 1 || typ.Out(0) != typeOf((*error)(nil)).Elem() {
+			return def, fmt.Errorf("invalid handler of %s.%s", pallet.name, c.name)
+		}
+
+		args := typ.In(1)
+		rt.handlers[types.CallIndex{SectionIndex: pallet.index, MethodIndex: uint8(i)}] = handler{
+			pallet: pallet.name,
+			name:   c.name,
+			args:   args,
+			fn:     fn,
+		}
+
+		def.Variant.Variants = append(def.Variant.Variants, types.Si1Variant{
+			Name:   types.Text(c.name),
+			Index:  types.U8(i),
+			Fields: r.fields(args),
+		})
+	}
+
+	return def, nil
+}
+
+// eventTypes returns the fields of EventRecords by pallet name
+func eventTypes() map[string][]reflect.StructField {
+	events := make(map[string][]reflect.StructField)
+
+	var walk func(t reflect.Type)
+	walk = func(t reflect.Type) {
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.Anonymous && field.Type.Kind() == reflect.Struct {
+				walk(field.Type)
+				continue
+			}
+
+			parts := strings.SplitN(field.Name, "_", 2)
+			if len(parts) != 2 || field.Type.Kind() != reflect.Slice {
+				continue
+			}
+
+			events[parts[0]] = append(events[parts[0]], field)
+		}
+	}
+
+	walk(typeOf(substrate.EventRecords{}))
+	return events
+}
+
+// eventFields returns the fields of an event type without the
+// phase and topics of the event record
+func eventFields(t reflect.Type) []reflect.StructField {
+	var fields []reflect.StructField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Name == "Phase" || field.Name == "Topics" {
+			continue
+		}
+
+		fields = append(fields, field)
+	}
+
+	return fields
+}
+
+// event returns the id of the event
+func (rt *runtime) event(pallet, name string) (types.EventID, error) {
+	id, ok := rt.events[fmt.Sprintf("%s.%s", pallet, name)]
+	if !ok {
+		return id, fmt.Errorf("event %s.%s not found", pallet, name)
+	}
+
+	return id, nil
+}
+
+// moduleError returns the dispatch error of the pallet error with given name
+func (rt *runtime) moduleError(pallet, name string) (types.DispatchError, error) {
+	for _, p := range rt.pallets {
+		if p.name != pallet {
+			continue
+		}
+
+		for i, e := range p.errors {
+			if e == name {
+				return types.DispatchError{HasModule: true, Module: p.index, Error: uint8(i)}, nil
+			}
+		}
+	}
+
+	return types.DispatchError{}, fmt.Errorf("error %s.%s not found", pallet, name)
+}
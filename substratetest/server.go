@@ -0,0 +1,439 @@
+package substratetest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/centrifuge/go-substrate-rpc-client/v4/types"
+	"github.com/gorilla/websocket"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// defaultBlockTime is the interval empty blocks are produced at
+	defaultBlockTime = 6 * time.Second
+
+	errMethodNotFound = -32601
+	errInvalidParams  = -32602
+	errInternal       = -32603
+	// errInvalidTransaction is the code substrate uses for extrinsics
+	// rejected by the transaction pool
+	errInvalidTransaction = 1010
+)
+
+// rpcError is a JSON-RPC error
+type rpcError struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+func (e *rpcError) Error() string {
+	return e.Message
+}
+
+func invalidParams(err error) error {
+	return &rpcError{Code: errInvalidParams, Message: err.Error()}
+}
+
+type request struct {
+	ID     json.RawMessage   `json:"id"`
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+}
+
+type response struct {
+	Version string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type notification struct {
+	Version string             `json:"jsonrpc"`
+	Method  string             `json:"method"`
+	Params  notificationParams `json:"params"`
+}
+
+type notificationParams struct {
+	Subscription string      `json:"subscription"`
+	Result       interface{} `json:"result"`
+}
+
+// ServerOption configures the server
+type ServerOption func(*Server)
+
+// WithBlockTime sets the interval empty blocks are produced at, the
+// client refuses nodes whose last block is older than 12 seconds
+func WithBlockTime(d time.Duration) ServerOption {
+	return func(s *Server) {
+		s.blockTime = d
+	}
+}
+
+// Server serves an in-memory chain over the substrate websocket JSON-RPC
+// API, so code using the client can be tested offline with
+// substrate.NewManager(server.URL()).
+type Server struct {
+	*Chain
+
+	blockTime time.Duration
+	listener  net.Listener
+	http      *http.Server
+	upgrader  websocket.Upgrader
+	done      chan struct{}
+	wg        sync.WaitGroup
+
+	m     sync.Mutex
+	conns map[*conn]struct{}
+}
+
+// NewServer starts a server on a random local port
+func NewServer(opts ...ServerOption) (*Server, error) {
+	chain, err := newChain()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create chain")
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to listen")
+	}
+
+	s := &Server{
+		Chain:     chain,
+		blockTime: defaultBlockTime,
+		listener:  listener,
+		done:      make(chan struct{}),
+		conns:     make(map[*conn]struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.http = &http.Server{Handler: http.HandlerFunc(s.serveHTTP)}
+
+	s.wg.Add(2)
+	go func() {
+		defer s.wg.Done()
+		if err := s.http.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Error().Err(err).Msg("mock chain server failed")
+		}
+	}()
+
+	go func() {
+		defer s.wg.Done()
+		s.produce()
+	}()
+
+	return s, nil
+}
+
+// URL returns the websocket url of the server
+func (s *Server) URL() string {
+	return fmt.Sprintf("ws://%s", s.listener.Addr())
+}
+
+// Close stops the server and closes all connections
+func (s *Server) Close() error {
+	close(s.done)
+	err := s.http.Close()
+
+	// websocket connections are hijacked so they are
+	// not closed by the http server
+	s.m.Lock()
+	for c := range s.conns {
+		c.ws.Close()
+	}
+	s.m.Unlock()
+
+	s.wg.Wait()
+	return err
+}
+
+// DropConnections closes all open connections, new connections are still
+// accepted so clients can reconnect
+func (s *Server) DropConnections() {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	for c := range s.conns {
+		c.ws.Close()
+	}
+}
+
+// produce seals empty blocks so the chain time keeps up with the clock
+func (s *Server) produce() {
+	ticker := time.NewTicker(s.blockTime)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			if err := s.NewBlock(); err != nil {
+				log.Error().Err(err).Msg("failed to seal block")
+			}
+		}
+	}
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	ws, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+
+	c := &conn{
+		srv:  s,
+		ws:   ws,
+		out:  make(chan interface{}, 1024),
+		subs: make(map[string]func()),
+		done: make(chan struct{}),
+	}
+
+	s.m.Lock()
+	s.conns[c] = struct{}{}
+	s.m.Unlock()
+
+	defer func() {
+		s.m.Lock()
+		delete(s.conns, c)
+		s.m.Unlock()
+	}()
+
+	go c.write()
+	c.read()
+}
+
+// conn is a websocket connection, all messages are written by a single
+// goroutine so responses and notifications are sent in order
+type conn struct {
+	srv  *Server
+	ws   *websocket.Conn
+	out  chan interface{}
+	done chan struct{}
+
+	m      sync.Mutex
+	subs   map[string]func()
+	nextID int
+}
+
+func (c *conn) send(msg interface{}) {
+	select {
+	case c.out <- msg:
+	case <-c.done:
+	}
+}
+
+func (c *conn) write() {
+	for {
+		select {
+		case <-c.done:
+			return
+		case msg := <-c.out:
+			if err := c.ws.WriteJSON(msg); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (c *conn) read() {
+	defer func() {
+		close(c.done)
+		c.ws.Close()
+
+		c.m.Lock()
+		defer c.m.Unlock()
+		for _, cancel := range c.subs {
+			cancel()
+		}
+	}()
+
+	for {
+		var req request
+		if err := c.ws.ReadJSON(&req); err != nil {
+			return
+		}
+
+		c.handle(req)
+	}
+}
+
+func (c *conn) handle(req request) {
+	resp := response{Version: "2.0", ID: req.ID}
+	result, after, err := c.call(req)
+	if err != nil {
+		var rerr *rpcError
+		if !errors.As(err, &rerr) {
+			rerr = &rpcError{Code: errInternal, Message: err.Error()}
+		}
+		resp.Error = rerr
+	} else {
+		resp.Result = result
+	}
+
+	c.send(resp)
+	if after != nil {
+		after()
+	}
+}
+
+// call executes the request, after is called once the response is sent
+func (c *conn) call(req request) (result interface{}, after func(), err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("mock chain panic: %v", r)
+		}
+	}()
+
+	if strings.Contains(req.Method, "_unsubscribe") {
+		var id string
+		if err := param(req.Params, 0, &id); err != nil {
+			return nil, nil, err
+		}
+
+		return c.unsubscribe(id), nil, nil
+	}
+
+	if subscribe, ok := subscriptions[req.Method]; ok {
+		sub := c.subscription(subscribe.notification)
+		if err := subscribe.fn(c.srv.Chain, sub, req.Params); err != nil {
+			c.unsubscribe(sub.id)
+			return nil, nil, err
+		}
+
+		return sub.id, sub.start, nil
+	}
+
+	method, ok := methods[req.Method]
+	if !ok {
+		return nil, nil, &rpcError{Code: errMethodNotFound, Message: fmt.Sprintf("Method not found: %s", req.Method)}
+	}
+
+	result, err = method(c.srv.Chain, req.Params)
+	return result, nil, err
+}
+
+// subscription sends notifications, they are held back until
+// the subscription id is sent to the client
+type subscription struct {
+	c      *conn
+	id     string
+	method string
+
+	m       sync.Mutex
+	started bool
+	pending []interface{}
+	cancel  func()
+}
+
+func (c *conn) subscription(method string) *subscription {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	c.nextID++
+	sub := &subscription{c: c, id: strconv.Itoa(c.nextID), method: method}
+	c.subs[sub.id] = func() {
+		sub.m.Lock()
+		cancel := sub.cancel
+		sub.m.Unlock()
+
+		if cancel != nil {
+			cancel()
+		}
+	}
+
+	return sub
+}
+
+func (c *conn) unsubscribe(id string) bool {
+	c.m.Lock()
+	cancel, ok := c.subs[id]
+	delete(c.subs, id)
+	c.m.Unlock()
+
+	if ok {
+		cancel()
+	}
+
+	return ok
+}
+
+// onCancel sets the function called when the client unsubscribes
+func (s *subscription) onCancel(fn func()) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	s.cancel = fn
+}
+
+func (s *subscription) notify(result interface{}) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if !s.started {
+		s.pending = append(s.pending, result)
+		return
+	}
+
+	s.send(result)
+}
+
+func (s *subscription) send(result interface{}) {
+	s.c.send(notification{
+		Version: "2.0",
+		Method:  s.method,
+		Params:  notificationParams{Subscription: s.id, Result: result},
+	})
+}
+
+func (s *subscription) start() {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	s.started = true
+	for _, result := range s.pending {
+		s.send(result)
+	}
+	s.pending = nil
+}
+
+// param decodes the parameter at index i, it's left untouched if missing
+func param(params []json.RawMessage, i int, v interface{}) error {
+	if i >= len(params) || string(params[i]) == "null" {
+		return nil
+	}
+
+	if err := json.Unmarshal(params[i], v); err != nil {
+		return invalidParams(errors.Wrapf(err, "invalid parameter %d", i))
+	}
+
+	return nil
+}
+
+// hashParam decodes an optional block hash parameter
+func hashParam(params []json.RawMessage, i int) (types.Hash, error) {
+	var hash types.Hash
+	var hex string
+	if err := param(params, i, &hex); err != nil || hex == "" {
+		return hash, err
+	}
+
+	hash, err := types.NewHashFromHexString(hex)
+	if err != nil {
+		return hash, invalidParams(err)
+	}
+
+	return hash, nil
+}
@@ -0,0 +1,108 @@
+package substratetest
+
+import (
+	"net"
+	"testing"
+
+	"github.com/centrifuge/go-substrate-rpc-client/v4/types"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	substrate "github.com/threefoldtech/substrate-client"
+)
+
+func TestServer(t *testing.T) {
+	require := require.New(t)
+
+	srv, err := NewServer()
+	require.NoError(err)
+	defer srv.Close()
+
+	mgr := substrate.NewManager(srv.URL())
+	defer mgr.Close()
+
+	cl, err := mgr.Substrate()
+	require.NoError(err)
+	defer cl.Close()
+
+	user, err := substrate.NewIdentityFromSr25519Phrase("//Bob")
+	require.NoError(err)
+
+	node, err := substrate.NewIdentityFromEd25519Phrase("//Charlie")
+	require.NoError(err)
+
+	// twins can't be created before accepting the terms and conditions
+	_, err = cl.CreateTwin(user, net.ParseIP("::1"))
+	require.Error(err)
+
+	twins := map[substrate.Identity]uint32{}
+	for _, identity := range []substrate.Identity{user, node} {
+		require.NoError(cl.AcceptTermsAndConditions(identity, "link", "hash"))
+
+		id, err := cl.CreateTwin(identity, net.ParseIP("::1"))
+		require.NoError(err)
+		twins[identity] = id
+	}
+	require.Equal(uint32(2), twins[node])
+
+	farm, err := srv.CreateFarm(twins[node], "farm", substrate.PublicIP{IP: "10.0.0.1/24", Gateway: "10.0.0.254"})
+	require.NoError(err)
+
+	nodeID, err := cl.CreateNode(node, substrate.Node{
+		FarmID:  types.U32(farm),
+		TwinID:  types.U32(twins[node]),
+		Country: "Belgium",
+	})
+	require.NoError(err)
+
+	loaded, err := cl.GetNode(nodeID)
+	require.NoError(err)
+	require.Equal("Belgium", loaded.Country)
+
+	contract, err := cl.CreateNodeContract(user, nodeID, nil, "deployment", 1)
+	require.NoError(err)
+
+	c, err := cl.GetContract(contract)
+	require.NoError(err)
+	require.Len(c.ContractType.NodeContract.PublicIPs, 1)
+	require.Equal("10.0.0.1/24", c.ContractType.NodeContract.PublicIPs[0].IP)
+
+	_, err = cl.CreateNodeContract(user, nodeID, nil, "deployment", 0)
+	require.ErrorIs(err, substrate.ErrContractIsNotUnique)
+
+	id, err := cl.GetContractWithHash(nodeID, "deployment")
+	require.NoError(err)
+	require.Equal(contract, id)
+
+	// only the owner twin can update or cancel the contract
+	_, err = cl.UpdateNodeContract(node, contract, nil, "other")
+	require.ErrorIs(err, substrate.ErrTwinNotAuthorizedToUpdateContract)
+	require.False(errors.Is(err, substrate.ErrTwinNotAuthorizedToCancelContract))
+
+	err = cl.CancelContract(node, contract)
+	require.ErrorIs(err, substrate.ErrTwinNotAuthorizedToCancelContract)
+
+	require.NoError(cl.CancelContract(user, contract))
+	_, err = cl.GetContract(contract)
+	require.ErrorIs(err, substrate.ErrNotFound)
+
+	// only the sudo key can certify nodes
+	require.Error(cl.SetNodeCertificate(user, nodeID, substrate.NodeCertification{IsCertified: true}))
+
+	// without sudo the call fails with a bad origin
+	conn, meta, err := mgr.Raw()
+	require.NoError(err)
+	defer conn.Client.Close()
+
+	call, err := types.NewCall(meta, "TfgridModule.set_node_certification", types.U32(nodeID), substrate.NodeCertification{IsCertified: true})
+	require.NoError(err)
+	_, err = cl.Call(conn, meta, user, call)
+	require.ErrorIs(err, substrate.ErrBadOrigin)
+
+	alice, err := substrate.NewIdentityFromSr25519Phrase("//Alice")
+	require.NoError(err)
+	require.NoError(cl.SetNodeCertificate(alice, nodeID, substrate.NodeCertification{IsCertified: true}))
+
+	loaded, err = cl.GetNode(nodeID)
+	require.NoError(err)
+	require.True(loaded.Certification.IsCertified)
+}
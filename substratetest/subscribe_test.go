@@ -0,0 +1,71 @@
+package substratetest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	substrate "github.com/threefoldtech/substrate-client"
+)
+
+func TestSubscribeEventsReconnect(t *testing.T) {
+	for _, finalized := range []bool{false, true} {
+		t.Run(fmt.Sprintf("finalized=%t", finalized), func(t *testing.T) {
+			require := require.New(t)
+
+			srv, err := NewServer(WithBlockTime(time.Hour))
+			require.NoError(err)
+			defer srv.Close()
+
+			mgr := substrate.NewManager(srv.URL())
+			defer mgr.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+			defer cancel()
+
+			sub, err := mgr.Substrate()
+			require.NoError(err)
+
+			ch, err := sub.SubscribeEvents(ctx, 1, finalized)
+			require.NoError(err)
+			// the subscription outlives the client
+			sub.Close()
+
+			// blocks keep coming while the subscription reconnects
+			go func() {
+				for ctx.Err() == nil {
+					if err := srv.NewBlock(); err != nil {
+						t.Error(err)
+						return
+					}
+					time.Sleep(20 * time.Millisecond)
+				}
+			}()
+
+			var blocks []substrate.BlockEvents
+			for len(blocks) < 30 {
+				block, ok := <-ch
+				require.True(ok, "subscription closed after %d blocks", len(blocks))
+				require.EqualValues(len(blocks)+1, block.Number, "blocks must not be skipped or duplicated")
+				blocks = append(blocks, block)
+
+				if len(blocks)%10 == 0 {
+					srv.DropConnections()
+				}
+			}
+
+			cl, err := mgr.Substrate()
+			require.NoError(err)
+			defer cl.Close()
+
+			for _, block := range blocks {
+				info, err := cl.GetBlockInfoAt(block.Number)
+				require.NoError(err)
+				require.Equal(info.Hash, block.Hash)
+				require.NotNil(block.Events)
+			}
+		})
+	}
+}
@@ -0,0 +1,344 @@
+package substratetest
+
+import (
+	"github.com/centrifuge/go-substrate-rpc-client/v4/types"
+	substrate "github.com/threefoldtech/substrate-client"
+)
+
+const (
+	tfgrid = "TfgridModule"
+
+	farmVersion = 3
+	twinVersion = 1
+	nodeVersion = 4
+)
+
+func tfgridPallet() pallet {
+	return pallet{
+		name:  tfgrid,
+		index: 11,
+		storage: []entry{
+			mapped("Farms", substrate.Farm{}, types.U32(0)),
+			plain("FarmID", types.U32(0)),
+			mapped("Nodes", substrate.Node{}, types.U32(0)),
+			plain("NodeID", types.U32(0)),
+			mapped("NodeIdByTwinID", types.U32(0), types.U32(0)),
+			mapped("Twins", substrate.Twin{}, types.U32(0)),
+			plain("TwinID", types.U32(0)),
+			mapped("TwinIdByAccountID", types.U32(0), types.AccountID{}),
+			mapped("UsersTermsAndConditions", []substrate.TermsAndConditions{}, types.AccountID{}),
+		},
+		calls: []call{
+			{name: "create_twin", fn: createTwin},
+			{name: "update_twin", fn: updateTwin},
+			{name: "user_accept_tc", fn: userAcceptTC},
+			{name: "create_node", fn: createNode},
+			{name: "update_node", fn: updateNode},
+			{name: "report_uptime", fn: reportUptime},
+			{name: "set_node_certification", fn: setNodeCertification},
+		},
+		errors: []string{
+			"TwinNotExists",
+			"TwinWithSameAccountIdExists",
+			"UserDidNotSignTermsAndConditions",
+			"FarmNotExists",
+			"NodeNotExists",
+			"NodeWithTwinIdExists",
+			"NodeUpdateNotAuthorized",
+		},
+	}
+}
+
+// twinOf returns the twin of the account
+func (d *dispatch) twinOf(account types.AccountID) (substrate.Twin, error) {
+	var twin substrate.Twin
+	var id types.U32
+	if !d.get(&id, tfgrid, "TwinIdByAccountID", account) || !d.get(&twin, tfgrid, "Twins", id) {
+		return twin, d.fail(tfgrid, "TwinNotExists")
+	}
+
+	return twin, nil
+}
+
+// nodeOf returns the node of the account
+func (d *dispatch) nodeOf(account types.AccountID) (substrate.Node, error) {
+	var node substrate.Node
+	twin, err := d.twinOf(account)
+	if err != nil {
+		return node, err
+	}
+
+	var id types.U32
+	if !d.get(&id, tfgrid, "NodeIdByTwinID", twin.ID) || !d.get(&node, tfgrid, "Nodes", id) {
+		return node, d.fail(tfgrid, "NodeNotExists")
+	}
+
+	return node, nil
+}
+
+// nextID increments the counter storage item and returns the new value
+func (d *dispatch) nextID(pallet, item string) types.U32 {
+	var id types.U32
+	d.get(&id, pallet, item)
+	id++
+	d.put(id, pallet, item)
+	return id
+}
+
+type createTwinArgs struct {
+	IP string
+}
+
+func createTwin(d *dispatch, args createTwinArgs) error {
+	signer, err := d.signer()
+	if err != nil {
+		return err
+	}
+
+	var conditions []substrate.TermsAndConditions
+	if !d.get(&conditions, tfgrid, "UsersTermsAndConditions", signer) {
+		return d.fail(tfgrid, "UserDidNotSignTermsAndConditions")
+	}
+
+	if d.get(new(types.U32), tfgrid, "TwinIdByAccountID", signer) {
+		return d.fail(tfgrid, "TwinWithSameAccountIdExists")
+	}
+
+	twin := substrate.Twin{
+		Versioned: substrate.Versioned{Version: twinVersion},
+		ID:        d.nextID(tfgrid, "TwinID"),
+		Account:   substrate.AccountID(signer),
+		IP:        args.IP,
+	}
+
+	d.put(twin, tfgrid, "Twins", twin.ID)
+	d.put(twin.ID, tfgrid, "TwinIdByAccountID", signer)
+	d.emit(tfgrid, "TwinStored", twin)
+	return nil
+}
+
+type updateTwinArgs struct {
+	IP string
+}
+
+func updateTwin(d *dispatch, args updateTwinArgs) error {
+	signer, err := d.signer()
+	if err != nil {
+		return err
+	}
+
+	twin, err := d.twinOf(signer)
+	if err != nil {
+		return err
+	}
+
+	twin.IP = args.IP
+	d.put(twin, tfgrid, "Twins", twin.ID)
+	d.emit(tfgrid, "TwinUpdated", twin)
+	return nil
+}
+
+type userAcceptTCArgs struct {
+	DocumentLink string
+	DocumentHash string
+}
+
+func userAcceptTC(d *dispatch, args userAcceptTCArgs) error {
+	signer, err := d.signer()
+	if err != nil {
+		return err
+	}
+
+	var conditions []substrate.TermsAndConditions
+	d.get(&conditions, tfgrid, "UsersTermsAndConditions", signer)
+	conditions = append(conditions, substrate.TermsAndConditions{
+		Account:      substrate.AccountID(signer),
+		Timestamp:    types.U64(d.now.Unix()),
+		DocumentLink: args.DocumentLink,
+		DocumentHash: args.DocumentHash,
+	})
+
+	d.put(conditions, tfgrid, "UsersTermsAndConditions", signer)
+	return nil
+}
+
+type createNodeArgs struct {
+	FarmID      types.U32
+	Resources   substrate.Resources
+	Location    substrate.Location
+	Country     string
+	City        string
+	Interfaces  []substrate.Interface
+	SecureBoot  bool
+	Virtualized bool
+	BoardSerial string
+}
+
+func createNode(d *dispatch, args createNodeArgs) error {
+	signer, err := d.signer()
+	if err != nil {
+		return err
+	}
+
+	twin, err := d.twinOf(signer)
+	if err != nil {
+		return err
+	}
+
+	if !d.get(&substrate.Farm{}, tfgrid, "Farms", args.FarmID) {
+		return d.fail(tfgrid, "FarmNotExists")
+	}
+
+	if d.get(new(types.U32), tfgrid, "NodeIdByTwinID", twin.ID) {
+		return d.fail(tfgrid, "NodeWithTwinIdExists")
+	}
+
+	node := substrate.Node{
+		Versioned:     substrate.Versioned{Version: nodeVersion},
+		ID:            d.nextID(tfgrid, "NodeID"),
+		FarmID:        args.FarmID,
+		TwinID:        twin.ID,
+		Resources:     args.Resources,
+		Location:      args.Location,
+		Country:       args.Country,
+		City:          args.City,
+		Created:       types.U64(d.now.Unix()),
+		Interfaces:    args.Interfaces,
+		Certification: substrate.NodeCertification{IsDiy: true},
+		SecureBoot:    args.SecureBoot,
+		Virtualized:   args.Virtualized,
+		BoardSerial:   args.BoardSerial,
+	}
+
+	d.put(node, tfgrid, "Nodes", node.ID)
+	d.put(node.ID, tfgrid, "NodeIdByTwinID", twin.ID)
+	d.emit(tfgrid, "NodeStored", node)
+	return nil
+}
+
+type updateNodeArgs struct {
+	NodeID      types.U32
+	FarmID      types.U32
+	Resources   substrate.Resources
+	Location    substrate.Location
+	Country     string
+	City        string
+	Interfaces  []substrate.Interface
+	SecureBoot  bool
+	Virtualized bool
+	BoardSerial string
+}
+
+func updateNode(d *dispatch, args updateNodeArgs) error {
+	signer, err := d.signer()
+	if err != nil {
+		return err
+	}
+
+	twin, err := d.twinOf(signer)
+	if err != nil {
+		return err
+	}
+
+	var node substrate.Node
+	if !d.get(&node, tfgrid, "Nodes", args.NodeID) {
+		return d.fail(tfgrid, "NodeNotExists")
+	}
+
+	if node.TwinID != twin.ID {
+		return d.fail(tfgrid, "NodeUpdateNotAuthorized")
+	}
+
+	if !d.get(&substrate.Farm{}, tfgrid, "Farms", args.FarmID) {
+		return d.fail(tfgrid, "FarmNotExists")
+	}
+
+	node.FarmID = args.FarmID
+	node.Resources = args.Resources
+	node.Location = args.Location
+	node.Country = args.Country
+	node.City = args.City
+	node.Interfaces = args.Interfaces
+	node.SecureBoot = args.SecureBoot
+	node.Virtualized = args.Virtualized
+	node.BoardSerial = args.BoardSerial
+
+	d.put(node, tfgrid, "Nodes", node.ID)
+	d.emit(tfgrid, "NodeUpdated", node)
+	return nil
+}
+
+type reportUptimeArgs struct {
+	Uptime types.U64
+}
+
+func reportUptime(d *dispatch, args reportUptimeArgs) error {
+	signer, err := d.signer()
+	if err != nil {
+		return err
+	}
+
+	node, err := d.nodeOf(signer)
+	if err != nil {
+		return err
+	}
+
+	d.emit(tfgrid, "NodeUptimeReported", node.ID, types.U64(d.now.Unix()), args.Uptime)
+	return nil
+}
+
+type setNodeCertificationArgs struct {
+	NodeID        types.U32
+	Certification substrate.NodeCertification
+}
+
+func setNodeCertification(d *dispatch, args setNodeCertificationArgs) error {
+	if !d.root {
+		return errBadOrigin
+	}
+
+	var node substrate.Node
+	if !d.get(&node, tfgrid, "Nodes", args.NodeID) {
+		return d.fail(tfgrid, "NodeNotExists")
+	}
+
+	node.Certification = args.Certification
+	d.put(node, tfgrid, "Nodes", node.ID)
+	d.emit(tfgrid, "NodeCertificationSet", node.ID, node.Certification)
+	return nil
+}
+
+// CreateFarm creates a farm owned by the twin with the given public
+// ips. Farms can't be created through the client so tests seed them
+// with CreateFarm.
+func (c *Chain) CreateFarm(twin uint32, name string, ips ...substrate.PublicIP) (uint32, error) {
+	var id uint32
+	err := c.exec(func(d *dispatch) error {
+		if !d.get(&substrate.Twin{}, tfgrid, "Twins", types.U32(twin)) {
+			return d.fail(tfgrid, "TwinNotExists")
+		}
+
+		// the certification variants are unexported so the
+		// NotCertified variant is decoded instead
+		var certification substrate.FarmCertification
+		if err := types.DecodeFromBytes([]byte{0}, &certification); err != nil {
+			return err
+		}
+
+		farm := substrate.Farm{
+			Versioned:         substrate.Versioned{Version: farmVersion},
+			ID:                d.nextID(tfgrid, "FarmID"),
+			Name:              name,
+			TwinID:            types.U32(twin),
+			CertificationType: certification,
+			PublicIPs:         ips,
+		}
+
+		id = uint32(farm.ID)
+		d.put(farm, tfgrid, "Farms", farm.ID)
+		d.emit(tfgrid, "FarmStored", farm)
+		return nil
+	})
+
+	return id, err
+}
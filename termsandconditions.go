@@ -27,15 +27,10 @@ func (s *Substrate) AcceptTermsAndConditions(identity Identity, documentLink str
 		return errors.Wrap(err, "failed to create call")
 	}
 
-	blockHash, err := s.Call(cl, meta, identity, c)
-	if err != nil {
+	if _, err := s.Call(cl, meta, identity, c); err != nil {
 		return errors.Wrap(err, "failed to accept terms and conditions")
 	}
 
-	if err := s.checkForError(cl, meta, blockHash, types.NewAccountID(identity.PublicKey())); err != nil {
-		return err
-	}
-
 	return nil
 }
 
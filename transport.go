@@ -0,0 +1,121 @@
+package substrate
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+
+	gethrpc "github.com/centrifuge/go-substrate-rpc-client/v4/gethrpc"
+	"github.com/gorilla/websocket"
+	"github.com/pkg/errors"
+)
+
+// Transport carries the raw JSON-RPC messages of a connection
+type Transport interface {
+	// Send sends a message to the node
+	Send(msg json.RawMessage) error
+	// Receive blocks until a message is received from the node
+	Receive() (json.RawMessage, error)
+	Close() error
+}
+
+// Dialer opens a transport to the given endpoint
+type Dialer func(ctx context.Context, endpoint string) (Transport, error)
+
+// wsTransport is a transport over a websocket connection
+type wsTransport struct {
+	ws *websocket.Conn
+	// m serializes writes, gorilla connections support
+	// only one concurrent writer
+	m sync.Mutex
+}
+
+// DialWebsocket is a Dialer that connects to a websocket endpoint
+func DialWebsocket(ctx context.Context, endpoint string) (Transport, error) {
+	ws, _, err := websocket.DefaultDialer.DialContext(ctx, endpoint, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to dial '%s'", endpoint)
+	}
+
+	return &wsTransport{ws: ws}, nil
+}
+
+func (t *wsTransport) Send(msg json.RawMessage) error {
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	return t.ws.WriteMessage(websocket.TextMessage, msg)
+}
+
+func (t *wsTransport) Receive() (json.RawMessage, error) {
+	_, msg, err := t.ws.ReadMessage()
+	return msg, err
+}
+
+func (t *wsTransport) Close() error {
+	return t.ws.Close()
+}
+
+// transportClient is an rpc client running over a transport
+type transportClient struct {
+	rpcClient
+	t   Transport
+	out *io.PipeWriter
+}
+
+// newTransportClient starts an rpc client that exchanges its messages over t
+func newTransportClient(ctx context.Context, t Transport, url string) (*transportClient, error) {
+	// the rpc client reads from inR and writes to outW
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+
+	cl, err := gethrpc.DialIO(ctx, inR, outW)
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		dec := json.NewDecoder(outR)
+		for {
+			var msg json.RawMessage
+			if err := dec.Decode(&msg); err != nil {
+				outR.CloseWithError(err)
+				return
+			}
+
+			if err := t.Send(msg); err != nil {
+				outR.CloseWithError(err)
+				return
+			}
+		}
+	}()
+
+	go func() {
+		for {
+			msg, err := t.Receive()
+			if err != nil {
+				// fail pending and future writes as well, the
+				// rpc client would wait forever for their response
+				inW.CloseWithError(err)
+				outR.CloseWithError(err)
+				return
+			}
+
+			if _, err := inW.Write(msg); err != nil {
+				return
+			}
+		}
+	}()
+
+	return &transportClient{rpcClient: rpcClient{Client: cl, url: url}, t: t, out: outW}, nil
+}
+
+// Close implements client.Client
+func (c *transportClient) Close() {
+	// the rpc client waits for its reader to fail before it
+	// returns from Close so the transport is closed first
+	c.t.Close()
+	c.Client.Close()
+	c.out.Close()
+}
@@ -107,10 +107,15 @@ func (s *Substrate) CreateTwin(identity Identity, ip net.IP) (uint32, error) {
 		return 0, errors.Wrap(err, "failed to create call")
 	}
 
-	if _, err := s.Call(cl, meta, identity, c); err != nil {
+	receipt, err := s.Submit(cl, meta, identity, c)
+	if err != nil {
 		return 0, errors.Wrap(err, "failed to create twin")
 	}
 
+	if events := receipt.Events.TfgridModule_TwinStored; len(events) > 0 {
+		return uint32(events[0].Twin.ID), nil
+	}
+
 	return s.GetTwinByPubKey(identity.PublicKey())
 }
 
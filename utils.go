@@ -1,7 +1,11 @@
 package substrate
 
 import (
+	"bytes"
 	"fmt"
+	"math/big"
+	"math/bits"
+	"reflect"
 	"time"
 
 	"github.com/centrifuge/go-substrate-rpc-client/v4/types"
@@ -11,39 +15,26 @@ import (
 	"golang.org/x/crypto/blake2b"
 )
 
+const (
+	// extrinsicTimeout is the max time to wait for an extrinsic
+	// status update before giving up
+	extrinsicTimeout = 30 * time.Second
+	// finalizationTimeout is the max time to wait for an extrinsic
+	// that is already in a block to be finalized
+	finalizationTimeout = 2 * time.Minute
+)
+
 var (
 	ErrIsUsurped = fmt.Errorf("Is Usurped")
+	// ErrFinalityTimeout is returned if the extrinsic block
+	// was not finalized in time
+	ErrFinalityTimeout = fmt.Errorf("finality timeout")
+	// ErrUnknownOutcome is returned if the extrinsic is included in a block
+	// but its outcome could not be read. The receipt has the block of the
+	// extrinsic, check the chain state before submitting it again.
+	ErrUnknownOutcome = fmt.Errorf("extrinsic included with unknown outcome")
 )
 
-// https://github.com/threefoldtech/tfchain_pallets/blob/bc9c5d322463aaf735212e428da4ea32b117dc24/pallet-smart-contract/src/lib.rs#L58
-var smartContractModuleErrors = []string{
-	"TwinNotExists",
-	"NodeNotExists",
-	"FarmNotExists",
-	"FarmHasNotEnoughPublicIPs",
-	"FarmHasNotEnoughPublicIPsFree",
-	"FailedToReserveIP",
-	"FailedToFreeIPs",
-	"ContractNotExists",
-	"TwinNotAuthorizedToUpdateContract",
-	"TwinNotAuthorizedToCancelContract",
-	"NodeNotAuthorizedToDeployContract",
-	"NodeNotAuthorizedToComputeReport",
-	"PricingPolicyNotExists",
-	"ContractIsNotUnique",
-	"NameExists",
-	"NameNotValid",
-	"InvalidContractType",
-	"TFTPriceValueError",
-	"NotEnoughResourcesOnNode",
-	"NodeNotAuthorizedToReportResources",
-	"MethodIsDeprecated",
-	"NodeHasActiveContracts",
-	"NodeHasRentContract",
-	"NodeIsNotDedicated",
-	"NodeNotAvailableToDeploy",
-}
-
 // Sign signs data with the private key under the given derivation path, returning the signature. Requires the subkey
 // command to be in path
 func signBytes(data []byte, privateKeyURI string, scheme subkey.Scheme) ([]byte, error) {
@@ -124,133 +115,511 @@ func (s *Substrate) sign(e *types.Extrinsic, signer Identity, o types.SignatureO
 	return nil
 }
 
-// Call call this extrinsic and retry if Usurped
-func (s *Substrate) Call(cl Conn, meta Meta, identity Identity, call types.Call) (hash types.Hash, err error) {
-	for {
-		hash, err := s.CallOnce(cl, meta, identity, call)
+// CallOption configures how an extrinsic is signed and submitted
+type CallOption func(*callOptions)
+
+type callOptions struct {
+	// period of a mortal era in blocks, 0 means immortal
+	period uint64
+	// checkpoint block of a mortal era, if not set
+	// the latest finalized block is used
+	checkpoint *uint32
+	tip        uint64
+	// finalized waits for the extrinsic block to be finalized
+	finalized bool
+}
 
-		if errors.Is(err, ErrIsUsurped) {
-			continue
-		}
+// WithMortalEra makes the extrinsic valid only for (at least) period blocks
+// starting from the latest finalized block. The period is rounded up to
+// a power of two between 4 and 65536.
+func WithMortalEra(period uint64) CallOption {
+	return func(o *callOptions) {
+		o.period = period
+		o.checkpoint = nil
+	}
+}
 
-		return hash, err
+// WithMortalEraAt is like WithMortalEra but the era starts at the given checkpoint block
+func WithMortalEraAt(period uint64, checkpoint uint32) CallOption {
+	return func(o *callOptions) {
+		o.period = period
+		o.checkpoint = &checkpoint
 	}
 }
 
-func (s *Substrate) CallOnce(cl Conn, meta Meta, identity Identity, call types.Call) (hash types.Hash, err error) {
-	// Create the extrinsic
-	ext := types.NewExtrinsic(call)
+// WithTip sets the tip paid to the block author to prioritize the extrinsic
+func WithTip(tip uint64) CallOption {
+	return func(o *callOptions) {
+		o.tip = tip
+	}
+}
 
-	genesisHash, err := cl.RPC.Chain.GetBlockHash(0)
+// WithFinalization waits until the extrinsic block is finalized instead of
+// returning as soon as the extrinsic is in a block. Use for operations that
+// must not be lost to a reorg.
+func WithFinalization() CallOption {
+	return func(o *callOptions) {
+		o.finalized = true
+	}
+}
+
+// WithCallOptions returns a shallow copy of s where opts are applied
+// to all extrinsics submitted through the copy. Options passed directly
+// to Call override the ones set here. Like WithContext, closing the copy
+// is a no-op.
+func (s *Substrate) WithCallOptions(opts ...CallOption) *Substrate {
+	cp := s.derive()
+	cp.opts = append(append([]CallOption{}, s.opts...), opts...)
+	return cp
+}
+
+func (s *Substrate) callOptions(opts []CallOption) callOptions {
+	var o callOptions
+	for _, opt := range s.opts {
+		opt(&o)
+	}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+// mortalEra builds the era of the given period that starts at the current block.
+// This follows the encoding of `Era::mortal` in substrate.
+func mortalEra(period uint64, current uint64) types.ExtrinsicEra {
+	// period is the next power of two in [4, 65536]
+	p := uint64(4)
+	for p < period && p < 1<<16 {
+		p <<= 1
+	}
+
+	phase := current % p
+	quantize := p >> 12
+	if quantize < 1 {
+		quantize = 1
+	}
+
+	low := bits.TrailingZeros64(p) - 1
+	if low < 1 {
+		low = 1
+	} else if low > 15 {
+		low = 15
+	}
+
+	encoded := uint16(low) | uint16(phase/quantize)<<4
+	return types.ExtrinsicEra{
+		IsMortalEra: true,
+		AsMortalEra: types.MortalEra{First: byte(encoded), Second: byte(encoded >> 8)},
+	}
+}
+
+// checkpoint returns the number and hash of the block a mortal era starts at
+func (s *Substrate) checkpoint(cl Conn, o callOptions) (uint64, types.Hash, error) {
+	if o.checkpoint != nil {
+		hash, err := cl.RPC.Chain.GetBlockHash(uint64(*o.checkpoint))
+		if err != nil {
+			return 0, hash, errors.Wrap(err, "failed to get checkpoint block hash")
+		}
+
+		return uint64(*o.checkpoint), hash, nil
+	}
+
+	hash, err := cl.RPC.Chain.GetFinalizedHead()
 	if err != nil {
-		return hash, errors.Wrap(err, "failed to get genesisHash")
+		return 0, hash, errors.Wrap(err, "failed to get finalized head")
 	}
 
-	rv, err := cl.RPC.State.GetRuntimeVersionLatest()
+	header, err := cl.RPC.Chain.GetHeader(hash)
 	if err != nil {
-		return hash, err
+		return 0, hash, errors.Wrap(err, "failed to get finalized header")
 	}
 
-	//node.Address =identity.PublicKey
-	account, err := s.getAccount(cl, meta, identity)
+	return uint64(header.Number), hash, nil
+}
+
+// Receipt describes the outcome of a submitted extrinsic
+type Receipt struct {
+	// BlockHash is the block the extrinsic is included in. If finalization
+	// was requested this is the finalized block.
+	BlockHash types.Hash
+	// BlockNumber is the number of the block the extrinsic is included in
+	BlockNumber uint32
+	// Finalized is set if BlockHash is known to be finalized
+	Finalized bool
+	// Retracted are blocks the extrinsic was included in
+	// before they were retracted by a reorg
+	Retracted []types.Hash
+	// Index is the index of the extrinsic in the block
+	Index uint32
+	// Events emitted by the extrinsic
+	Events EventRecords
+	// Fee paid by the signer for the extrinsic
+	Fee *big.Int
+	// Success is set if the extrinsic was dispatched successfully
+	Success bool
+	// Error is the reason the extrinsic failed if not successful
+	Error *DispatchError
+
+	// ordered are the events of the extrinsic in the order they were emitted
+	ordered []eventRecord
+}
+
+// Reorged returns true if the extrinsic was included in
+// a block that was later retracted
+func (r *Receipt) Reorged() bool {
+	return len(r.Retracted) > 0
+}
+
+// Call call this extrinsic and retry if Usurped. It fails with a *DispatchError
+// if the extrinsic was included but failed on chain, or with ErrUnknownOutcome
+// if it was included but its outcome could not be read.
+func (s *Substrate) Call(cl Conn, meta Meta, identity Identity, call types.Call, opts ...CallOption) (hash types.Hash, err error) {
+	receipt, err := s.Submit(cl, meta, identity, call, opts...)
+	return receipt.BlockHash, err
+}
+
+// CallOnce is like Call but does not retry if Usurped
+func (s *Substrate) CallOnce(cl Conn, meta Meta, identity Identity, call types.Call, opts ...CallOption) (hash types.Hash, err error) {
+	receipt, err := s.submit(cl, meta, identity, call, s.callOptions(opts))
+	return receipt.BlockHash, err
+}
+
+// Submit submits the extrinsic and retry if Usurped. Unlike Call it returns
+// the full receipt details of the extrinsic.
+func (s *Substrate) Submit(cl Conn, meta Meta, identity Identity, call types.Call, opts ...CallOption) (receipt Receipt, err error) {
+	options := s.callOptions(opts)
+	ctx := s.Context()
+	for {
+		receipt, err := s.submit(cl, meta, identity, call, options)
+
+		if errors.Is(err, ErrIsUsurped) && ctx.Err() == nil {
+			continue
+		}
+
+		return receipt, err
+	}
+}
+
+func (s *Substrate) submit(cl Conn, meta Meta, identity Identity, call types.Call, options callOptions) (receipt Receipt, err error) {
+	start := time.Now()
+	outcome := outcomeError
+	defer func() {
+		s.metrics.observeExtrinsic(outcome, start)
+	}()
+
+	nonce, err := s.nonces.acquire(s, cl, meta, identity)
 	if err != nil {
-		return hash, errors.Wrap(err, "failed to get account")
+		return receipt, errors.Wrap(err, "failed to get nonce")
 	}
+	defer s.nonces.release(identity)
+
+	// the nonce is synced again with the chain only if the extrinsic
+	// provably didn't consume it, or if it's waiting for a lower nonce
+	// that never made it to the pool. once in a block the nonce is
+	// consumed even if the call failed, and resyncing would hand out
+	// the nonces still held by other goroutines
+	unused := false
+	defer func() {
+		if unused {
+			s.nonces.resync(identity, nonce)
+		}
+	}()
 
-	o := types.SignatureOptions{
-		BlockHash:          genesisHash,
-		Era:                types.ExtrinsicEra{IsMortalEra: false},
-		GenesisHash:        genesisHash,
-		Nonce:              types.NewUCompactFromUInt(uint64(account.Nonce)),
-		SpecVersion:        rv.SpecVersion,
-		Tip:                types.NewUCompactFromUInt(0),
-		TransactionVersion: 1,
+	ext, err := s.extrinsic(cl, identity, call, nonce, options)
+	if err != nil {
+		unused = true
+		return receipt, err
 	}
 
-	err = s.sign(&ext, identity, o)
+	enc, err := types.EncodeToHexString(ext)
 	if err != nil {
-		return hash, errors.Wrap(err, "failed to sign")
+		unused = true
+		return receipt, errors.Wrap(err, "failed to encode extrinsic")
 	}
 
 	// Send the extrinsic
-	sub, err := cl.RPC.Author.SubmitAndWatchExtrinsic(ext)
+	ch := make(chan types.ExtrinsicStatus)
+	sub, err := subscribe(cl, ch, "author", "submitAndWatchExtrinsic", "unwatchExtrinsic", "extrinsicUpdate", enc)
 	if err != nil {
-		return hash, errors.Wrap(err, "failed to submit extrinsic")
+		unused = true
+		return receipt, errors.Wrap(err, "failed to submit extrinsic")
 	}
 
 	defer sub.Unsubscribe()
 
-	ch := sub.Chan()
+	ctx := s.Context()
 	ech := sub.Err()
+	// future is set while the extrinsic waits in
+	// the pool for a lower nonce of the identity
+	future := false
 
-loop:
 	for {
+		timeout := extrinsicTimeout
+		if receipt.BlockHash != (types.Hash{}) {
+			// already in a block, waiting for finalization
+			timeout = finalizationTimeout
+		}
+
 		select {
 		case err := <-ech:
-			return hash, errors.Wrap(err, "error failed on extrinsic status")
-		case <-time.After(30 * time.Second):
-			return hash, fmt.Errorf("extrinsic timeout waiting for block")
+			return receipt, errors.Wrap(err, "error failed on extrinsic status")
+		case <-ctx.Done():
+			return receipt, errors.Wrap(ctx.Err(), "stopped waiting for extrinsic")
+		case <-time.After(timeout):
+			outcome = outcomeTimeout
+			unused = future
+			return receipt, fmt.Errorf("extrinsic timeout waiting for block")
 		case event := <-ch:
-			if event.IsReady || event.IsBroadcast {
+			future = event.IsFuture
+			if event.IsReady || event.IsBroadcast || event.IsFuture {
 				continue
 			} else if event.IsInBlock {
-				hash = event.AsInBlock
-				break loop
+				receipt.BlockHash = event.AsInBlock
+				if !options.finalized {
+					outcome = outcomeInBlock
+					return receipt, s.inspect(cl, &receipt, ext)
+				}
+			} else if event.IsRetracted {
+				// the block was reorged out, the extrinsic goes back
+				// to the pool and will be included in another block
+				log.Warn().Str("block", event.AsRetracted.Hex()).Msg("extrinsic block was retracted")
+				receipt.Retracted = append(receipt.Retracted, event.AsRetracted)
+				receipt.BlockHash = types.Hash{}
 			} else if event.IsFinalized {
-				// we shouldn't hit this case
-				// any more since InBlock will always
-				// happen first we leave it only
-				// as a safety net
-				hash = event.AsFinalized
-				break loop
-			} else if event.IsDropped || event.IsInvalid {
-				return hash, fmt.Errorf("failed to make call")
+				// if finalization is not requested we shouldn't hit
+				// this case since InBlock will always happen first
+				// we leave it only as a safety net
+				receipt.BlockHash = event.AsFinalized
+				receipt.Finalized = true
+				outcome = outcomeFinalized
+				return receipt, s.inspect(cl, &receipt, ext)
+			} else if event.IsFinalityTimeout {
+				outcome = outcomeFinalityTimeout
+				return receipt, errors.Wrapf(ErrFinalityTimeout, "block '%s'", event.AsFinalityTimeout.Hex())
+			} else if event.IsDropped {
+				outcome = outcomeDropped
+				unused = true
+				return receipt, fmt.Errorf("failed to make call")
+			} else if event.IsInvalid {
+				outcome = outcomeInvalid
+				unused = true
+				return receipt, fmt.Errorf("failed to make call")
 			} else if event.IsUsurped {
-				return hash, ErrIsUsurped
+				outcome = outcomeUsurped
+				unused = true
+				return receipt, ErrIsUsurped
 			} else {
-				log.Error().Err(err).Msgf("extrinsic block in an unhandled state: %+v", event)
+				log.Error().Msgf("extrinsic block in an unhandled state: %+v", event)
 			}
 		}
 	}
+}
+
+// extrinsic creates the extrinsic for the call signed by identity
+func (s *Substrate) extrinsic(cl Conn, identity Identity, call types.Call, nonce uint64, options callOptions) (ext types.Extrinsic, err error) {
+	// Create the extrinsic
+	ext = types.NewExtrinsic(call)
+
+	genesisHash, err := cl.RPC.Chain.GetBlockHash(0)
+	if err != nil {
+		return ext, errors.Wrap(err, "failed to get genesisHash")
+	}
+
+	rv, err := cl.RPC.State.GetRuntimeVersionLatest()
+	if err != nil {
+		return ext, err
+	}
+
+	o := types.SignatureOptions{
+		BlockHash:          genesisHash,
+		Era:                types.ExtrinsicEra{IsImmortalEra: true},
+		GenesisHash:        genesisHash,
+		Nonce:              types.NewUCompactFromUInt(nonce),
+		SpecVersion:        rv.SpecVersion,
+		Tip:                types.NewUCompactFromUInt(options.tip),
+		TransactionVersion: rv.TransactionVersion,
+	}
+
+	if options.period > 0 {
+		number, checkpoint, err := s.checkpoint(cl, options)
+		if err != nil {
+			return ext, err
+		}
+
+		o.Era = mortalEra(options.period, number)
+		o.BlockHash = checkpoint
+	}
 
-	return hash, nil
+	if err := s.sign(&ext, identity, o); err != nil {
+		return ext, errors.Wrap(err, "failed to sign")
+	}
+
+	return ext, nil
 }
 
-func (s *Substrate) checkForError(cl Conn, meta Meta, blockHash types.Hash, signer types.AccountID) error {
-	key, err := types.CreateStorageKey(meta, "System", "Events", nil, nil)
+// inspect finds the extrinsic in the receipt block and collects the events
+// it emitted. It returns a *DispatchError if the extrinsic (or the call wrapped
+// by sudo) failed, or ErrUnknownOutcome if the extrinsic is in the block but
+// its outcome can't be read. The events are decoded with the metadata of the
+// block so a runtime upgrade since the extrinsic was submitted is handled.
+func (s *Substrate) inspect(cl Conn, receipt *Receipt, ext types.Extrinsic) error {
+	unknown := func(err error, msg string) error {
+		return errors.Wrapf(ErrUnknownOutcome, "block '%s': %s: %s", receipt.BlockHash.Hex(), msg, err)
+	}
+
+	encoded, err := types.EncodeToBytes(ext)
 	if err != nil {
-		return err
+		return unknown(err, "failed to encode extrinsic")
 	}
 
-	raw, err := cl.RPC.State.GetStorageRaw(key, blockHash)
+	block, err := cl.RPC.Chain.GetBlock(receipt.BlockHash)
 	if err != nil {
-		return err
+		return unknown(err, "failed to get extrinsic block")
 	}
 
-	block, err := cl.RPC.Chain.GetBlock(blockHash)
+	receipt.BlockNumber = uint32(block.Block.Header.Number)
+
+	index := -1
+	for i, xt := range block.Block.Extrinsics {
+		data, err := types.EncodeToBytes(xt)
+		if err != nil {
+			return unknown(err, "failed to encode block extrinsic")
+		}
+
+		if bytes.Equal(data, encoded) {
+			index = i
+			break
+		}
+	}
+
+	if index < 0 {
+		return errors.Wrapf(ErrUnknownOutcome, "extrinsic not found in block '%s'", receipt.BlockHash.Hex())
+	}
+
+	receipt.Index = uint32(index)
+	receipt.Fee = big.NewInt(0)
+
+	meta, err := s.metas.at(cl, receipt.BlockHash)
 	if err != nil {
-		return err
+		return unknown(err, "failed to get block metadata")
 	}
 
-	events := EventRecords{}
-	err = types.EventRecordsRaw(*raw).DecodeEventRecords(meta, &events)
+	key, err := types.CreateStorageKey(meta, "System", "Events", nil, nil)
 	if err != nil {
-		log.Debug().Msgf("Failed to decode event %+v", err)
-		return nil
-	}
-
-	if len(events.System_ExtrinsicFailed) > 0 {
-		for _, e := range events.System_ExtrinsicFailed {
-			who := block.Block.Extrinsics[e.Phase.AsApplyExtrinsic].Signature.Signer.AsID
-			if signer == who {
-				if int(e.DispatchError.Error) >= len(smartContractModuleErrors) {
-					return fmt.Errorf("error with code %d occured", e.DispatchError.Error)
-				} else {
-					return fmt.Errorf(smartContractModuleErrors[e.DispatchError.Error])
-				}
-			}
+		return unknown(err, "failed to create events key")
+	}
+
+	raw, err := cl.RPC.State.GetStorageRaw(key, receipt.BlockHash)
+	if err != nil {
+		return unknown(err, "failed to get block events")
+	}
+
+	events, err := decodeEventList(meta, *raw)
+	if err != nil {
+		return unknown(err, "failed to decode block events")
+	}
+
+	for _, event := range events {
+		if event.Phase.IsApplyExtrinsic && event.Phase.AsApplyExtrinsic == receipt.Index {
+			receipt.ordered = append(receipt.ordered, event)
+			appendEvents(&receipt.Events, &event.Event)
 		}
 	}
 
+	receipt.Fee = extrinsicFee(&receipt.Events, ext.Signature.Signer.AsID)
+
+	if receipt.Error = extrinsicError(meta, &receipt.Events); receipt.Error != nil {
+		return receipt.Error
+	}
+
+	receipt.Success = true
 	return nil
 }
+
+// extrinsicError gets the dispatch error from the events of a single extrinsic,
+// or nil if it succeeded
+func extrinsicError(meta Meta, events *EventRecords) (err *DispatchError) {
+	for _, e := range events.System_ExtrinsicFailed {
+		err = newDispatchError(meta, e.DispatchError)
+	}
+
+	for _, e := range events.Sudo_Sudid {
+		if !e.Result.Ok {
+			err = newDispatchError(meta, e.Result.Error)
+		}
+	}
+
+	return err
+}
+
+// extrinsicFee gets the fee paid by signer from the extrinsic events. Newer runtimes
+// emit TransactionFeePaid, older ones only have the Withdraw of the fee.
+func extrinsicFee(events *EventRecords, signer types.AccountID) *big.Int {
+	fee := big.NewInt(0)
+	for _, e := range events.TransactionPayment_TransactionFeePaid {
+		if e.Who == signer {
+			fee.Add(fee, e.ActualFee.Int)
+		}
+	}
+
+	if len(events.TransactionPayment_TransactionFeePaid) > 0 {
+		return fee
+	}
+
+	for _, e := range events.Balances_Withdraw {
+		if e.Who == signer {
+			fee.Add(fee, e.Amount.Int)
+		}
+	}
+
+	return fee
+}
+
+// appendEvents appends all the events of src to dst
+func appendEvents(dst, src *EventRecords) {
+	appendRecords(reflect.ValueOf(dst).Elem(), reflect.ValueOf(src).Elem())
+}
+
+func appendRecords(dst, src reflect.Value) {
+	for i := 0; i < src.NumField(); i++ {
+		field := src.Field(i)
+		switch field.Kind() {
+		case reflect.Struct:
+			// embedded event records
+			appendRecords(dst.Field(i), field)
+		case reflect.Slice:
+			if field.Len() > 0 {
+				dst.Field(i).Set(reflect.AppendSlice(dst.Field(i), field))
+			}
+		}
+	}
+}
+
+// filterEvents returns only the events emitted by the extrinsic with given index
+func filterEvents(events *EventRecords, index uint32) (filtered EventRecords) {
+	filterRecords(reflect.ValueOf(events).Elem(), reflect.ValueOf(&filtered).Elem(), index)
+	return
+}
+
+func filterRecords(src, dst reflect.Value, index uint32) {
+	for i := 0; i < src.NumField(); i++ {
+		field := src.Field(i)
+		switch field.Kind() {
+		case reflect.Struct:
+			// embedded event records
+			filterRecords(field, dst.Field(i), index)
+		case reflect.Slice:
+			for j := 0; j < field.Len(); j++ {
+				event := field.Index(j)
+				phase, ok := event.FieldByName("Phase").Interface().(types.Phase)
+				if !ok || !phase.IsApplyExtrinsic || phase.AsApplyExtrinsic != index {
+					continue
+				}
+
+				dst.Field(i).Set(reflect.Append(dst.Field(i), event))
+			}
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package substrate
+
+import (
+	"testing"
+
+	"github.com/centrifuge/go-substrate-rpc-client/v4/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMortalEra(t *testing.T) {
+	require := require.New(t)
+
+	cases := []struct {
+		period  uint64
+		current uint64
+		first   byte
+		second  byte
+	}{
+		// vectors from substrate `Era` codec tests
+		{period: 64, current: 42, first: 5 + 42%16*16, second: 42 / 16},
+		{period: 4, current: 6, first: 0x21, second: 0x00},
+		// period is rounded up to the next power of two
+		{period: 50, current: 42, first: 5 + 42%16*16, second: 42 / 16},
+		// and clamped to 65536 where the phase is quantized
+		{period: 1000000, current: 1000001, first: 0x4f, second: 0x42},
+	}
+
+	for _, c := range cases {
+		era := mortalEra(c.period, c.current)
+		require.True(era.IsMortalEra)
+		require.Equal(types.MortalEra{First: c.first, Second: c.second}, era.AsMortalEra, "period: %d, current: %d", c.period, c.current)
+	}
+}
+
+func TestFilterEvents(t *testing.T) {
+	require := require.New(t)
+
+	apply := func(index uint32) types.Phase {
+		return types.Phase{IsApplyExtrinsic: true, AsApplyExtrinsic: index}
+	}
+
+	var events EventRecords
+	events.System_ExtrinsicSuccess = []types.EventSystemExtrinsicSuccess{
+		{Phase: apply(0)},
+		{Phase: apply(1)},
+	}
+	events.System_ExtrinsicFailed = []types.EventSystemExtrinsicFailed{
+		{Phase: apply(2)},
+	}
+	events.SmartContractModule_ContractCreated = []ContractCreated{
+		{Phase: types.Phase{IsFinalization: true}},
+		{Phase: apply(1), Contract: Contract{ContractID: 10}},
+	}
+
+	filtered := filterEvents(&events, 1)
+	require.Len(filtered.System_ExtrinsicSuccess, 1)
+	require.Empty(filtered.System_ExtrinsicFailed)
+	require.Len(filtered.SmartContractModule_ContractCreated, 1)
+	require.EqualValues(10, filtered.SmartContractModule_ContractCreated[0].Contract.ContractID)
+}
@@ -41,3 +41,16 @@ func (r *ValidatorRequestState) Decode(decoder scale.Decoder) error {
 
 	return nil
 }
+
+// Encode implementation
+func (r ValidatorRequestState) Encode(encoder scale.Encoder) (err error) {
+	if r.IsCreated {
+		err = encoder.PushByte(0)
+	} else if r.IsApproved {
+		err = encoder.PushByte(1)
+	} else if r.IsValidating {
+		err = encoder.PushByte(2)
+	}
+
+	return
+}